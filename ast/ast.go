@@ -47,7 +47,11 @@ func (ls *LetStatement) String() string {
     var out bytes.Buffer
 
     out.WriteString(ls.TokenLiteral() + " ")
-    out.WriteString(ls.Name.String())
+    if ls.Pattern != nil {
+        out.WriteString(ls.Pattern.String())
+    } else {
+        out.WriteString(ls.Name.String())
+    }
     out.WriteString(" = ")
 
     if ls.Value != nil {
@@ -84,11 +88,13 @@ func (es *ExpressionStatement) String() string {
 type LetStatement struct {
     Token   token.Token //token.LET token
     Name    *Identifier
+    Pattern Expression //an ArrayPattern or HashPattern for destructuring lets, otherwise nil
     Value   Expression 
 }
 
 func (ls * LetStatement) statementNode(){}
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
+func (ls *LetStatement) IsConst() bool { return ls.Token.Type == token.CONST }
 
 type Identifier struct {
     Token   token.Token //token.IDENT token
@@ -107,6 +113,60 @@ type ReturnStatement struct {
 func (rs *ReturnStatement) statementNode(){}
 func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
 
+type ThrowStatement struct {
+    Token       token.Token //token.THROW token
+    Value       Expression
+}
+
+func (ts *ThrowStatement) statementNode(){}
+func (ts *ThrowStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *ThrowStatement) String() string { return ts.TokenLiteral() + " " + ts.Value.String() + ";" }
+
+type DeferStatement struct {
+    Token       token.Token //token.DEFER token
+    Call        Expression
+}
+
+func (ds *DeferStatement) statementNode(){}
+func (ds *DeferStatement) TokenLiteral() string { return ds.Token.Literal }
+func (ds *DeferStatement) String() string { return ds.TokenLiteral() + " " + ds.Call.String() + ";" }
+
+type ImportStatement struct {
+    Token       token.Token //token.IMPORT token
+    Path        *StringLiteral
+    Alias       *Identifier
+}
+
+func (is *ImportStatement) statementNode(){}
+func (is *ImportStatement) TokenLiteral() string { return is.Token.Literal }
+func (is *ImportStatement) String() string {
+    return is.TokenLiteral() + " \"" + is.Path.Value + "\" as " + is.Alias.String() + ";"
+}
+
+type ExportStatement struct {
+    Token       token.Token //token.EXPORT token
+    Statement   Statement //a let, const or func statement
+}
+
+func (es *ExportStatement) statementNode(){}
+func (es *ExportStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ExportStatement) String() string { return es.TokenLiteral() + " " + es.Statement.String() }
+
+// Names returns the names the exported statement declares.
+func (es *ExportStatement) Names() []string {
+    switch stmt := es.Statement.(type) {
+    case *LetStatement:
+        if stmt.Pattern != nil {
+            return PatternNames(stmt.Pattern)
+        }
+        return []string{stmt.Name.Value}
+    case *FunctionStatement:
+        return []string{stmt.Name.Value}
+    }
+
+    return nil
+}
+
 type ExpressionStatement struct {
     Token       token.Token //the first token present in the expression
     Expression  Expression
@@ -199,6 +259,7 @@ func (ie *IfExpression) String() string {
 type BlockStatement struct {
     Token           token.Token
     Statements      []Statement
+    End             token.Token //the closing } token, zero for an arrow function's expression body
 }
 
 func (bs *BlockStatement) expressionNode(){}
@@ -213,9 +274,45 @@ func (bs *BlockStatement) String() string {
     return out.String()
 }
 
+type TryExpression struct {
+    Token       token.Token //the try token
+    Block       *BlockStatement
+    Param       *Identifier //the name bound in the catch block, if any
+    Catch       *BlockStatement
+    Finally     *BlockStatement
+}
+
+func (te *TryExpression) expressionNode(){}
+func (te *TryExpression) TokenLiteral() string { return te.Token.Literal }
+func (te *TryExpression) String() string {
+    var out bytes.Buffer
+
+    out.WriteString("try ")
+    out.WriteString(te.Block.String())
+
+    if te.Catch != nil {
+        out.WriteString(" catch")
+        if te.Param != nil {
+            out.WriteString("(" + te.Param.String() + ")")
+        }
+        out.WriteString(" ")
+        out.WriteString(te.Catch.String())
+    }
+
+    if te.Finally != nil {
+        out.WriteString(" finally ")
+        out.WriteString(te.Finally.String())
+    }
+
+    return out.String()
+}
+
 type FunctionLiteral struct {
-    Token       token.Token //this is the func token specifically
+    Token       token.Token //the func token, or the => token for arrow functions
+    Name        string
     Parameters  []*Identifier
+    Defaults    []Expression //parallel to Parameters, nil where there is no default
+    Rest        *Identifier //the ...rest parameter, if any
     Body        *BlockStatement
 }
 
@@ -224,12 +321,21 @@ func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
 func (fl *FunctionLiteral) String() string {
     var out bytes.Buffer
 
-    params := []string{}
-    for _, p := range fl.Parameters {
-        params = append(params, p.String())
+    params := ParameterStrings(fl.Parameters, fl.Defaults, fl.Rest)
+
+    if fl.Token.Type == token.ARROW {
+        out.WriteString("(")
+        out.WriteString(strings.Join(params, ", "))
+        out.WriteString(") => ")
+        out.WriteString(fl.Body.String())
+
+        return out.String()
     }
 
     out.WriteString(fl.TokenLiteral())
+    if fl.Name != "" {
+        out.WriteString(" " + fl.Name)
+    }
     out.WriteString("(")
     out.WriteString(strings.Join(params, ", "))
     out.WriteString(") ")
@@ -238,6 +344,59 @@ func (fl *FunctionLiteral) String() string {
     return out.String()
 }
 
+func ParameterStrings(params []*Identifier, defaults []Expression, rest *Identifier) []string {
+    out := []string{}
+
+    for i, p := range params {
+        if i < len(defaults) && defaults[i] != nil {
+            out = append(out, p.String() + " = " + defaults[i].String())
+        } else {
+            out = append(out, p.String())
+        }
+    }
+
+    if rest != nil {
+        out = append(out, "..." + rest.String())
+    }
+
+    return out
+}
+
+type MacroLiteral struct {
+    Token       token.Token //the macro token
+    Parameters  []*Identifier
+    Body        *BlockStatement
+}
+
+func (ml *MacroLiteral) expressionNode(){}
+func (ml *MacroLiteral) TokenLiteral() string { return ml.Token.Literal }
+func (ml *MacroLiteral) String() string {
+    var out bytes.Buffer
+
+    params := []string{}
+    for _, p := range ml.Parameters {
+        params = append(params, p.String())
+    }
+
+    out.WriteString(ml.TokenLiteral())
+    out.WriteString("(")
+    out.WriteString(strings.Join(params, ", "))
+    out.WriteString(") ")
+    out.WriteString(ml.Body.String())
+
+    return out.String()
+}
+
+type FunctionStatement struct {
+    Token       token.Token //the func token
+    Name        *Identifier
+    Function    *FunctionLiteral
+}
+
+func (fs *FunctionStatement) statementNode(){}
+func (fs *FunctionStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *FunctionStatement) String() string { return fs.Function.String() }
+
 type CallExpression struct {
     Token       token.Token //left parenthesis token '('
     Function    Expression //either an Identifier or FunctionLiteral
@@ -262,6 +421,25 @@ func (ce *CallExpression) String() string {
     return out.String()
 }
 
+type SpreadExpression struct {
+    Token   token.Token //the ... token
+    Value   Expression
+}
+
+func (se *SpreadExpression) expressionNode(){}
+func (se *SpreadExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SpreadExpression) String() string { return "..." + se.Value.String() }
+
+type KeywordArgument struct {
+    Token   token.Token //the keyword's identifier token
+    Name    *Identifier
+    Value   Expression
+}
+
+func (ka *KeywordArgument) expressionNode(){}
+func (ka *KeywordArgument) TokenLiteral() string { return ka.Token.Literal }
+func (ka *KeywordArgument) String() string { return ka.Name.String() + ": " + ka.Value.String() }
+
 type StringLiteral struct {
     Token   token.Token
     Value   string
@@ -274,6 +452,7 @@ func (sl *StringLiteral) String() string { return sl.Token.Literal }
 type ArrayLiteral struct {
     Token       token.Token
     Elements    []Expression
+    End         token.Token //the closing ] token
 }
 
 func (al *ArrayLiteral) expressionNode(){}
@@ -313,9 +492,124 @@ func (ie *IndexExpression) String() string {
     return out.String()
 }
 
+type SliceExpression struct {
+    Token       token.Token //the [ token, or ?. when Optional
+    Left        Expression
+    Start       Expression //nil when omitted
+    End         Expression //nil when omitted
+    Step        Expression //nil when omitted
+    Optional    bool //true for ?.[start:end]
+}
+
+func (se *SliceExpression) expressionNode(){}
+func (se *SliceExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SliceExpression) String() string {
+    var out bytes.Buffer
+
+    out.WriteString("(")
+    out.WriteString(se.Left.String())
+    if se.Optional {
+        out.WriteString("?.")
+    }
+    out.WriteString("[")
+    if se.Start != nil {
+        out.WriteString(se.Start.String())
+    }
+    out.WriteString(":")
+    if se.End != nil {
+        out.WriteString(se.End.String())
+    }
+    if se.Step != nil {
+        out.WriteString(":")
+        out.WriteString(se.Step.String())
+    }
+    out.WriteString("])")
+
+    return out.String()
+}
+
+type PropertyExpression struct {
+    Token       token.Token //the . token
+    Object      Expression
+    Property    *Identifier
+}
+
+func (pe *PropertyExpression) expressionNode(){}
+func (pe *PropertyExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PropertyExpression) String() string {
+    return "(" + pe.Object.String() + "." + pe.Property.String() + ")"
+}
+
+type OptionalPropertyExpression struct {
+    Token       token.Token //the ?. token
+    Object      Expression
+    Property    *Identifier
+}
+
+func (op *OptionalPropertyExpression) expressionNode(){}
+func (op *OptionalPropertyExpression) TokenLiteral() string { return op.Token.Literal }
+func (op *OptionalPropertyExpression) String() string {
+    return "(" + op.Object.String() + "?." + op.Property.String() + ")"
+}
+
+type OptionalIndexExpression struct {
+    Token       token.Token //the ?. token
+    Left        Expression
+    Index       Expression
+}
+
+func (oi *OptionalIndexExpression) expressionNode(){}
+func (oi *OptionalIndexExpression) TokenLiteral() string { return oi.Token.Literal }
+func (oi *OptionalIndexExpression) String() string {
+    return "(" + oi.Left.String() + "?.[" + oi.Index.String() + "])"
+}
+
+type RangeExpression struct {
+    Token       token.Token //the .. or ..< token
+    Start       Expression
+    End         Expression
+    Inclusive   bool //true for .., false for ..<
+}
+
+func (re *RangeExpression) expressionNode(){}
+func (re *RangeExpression) TokenLiteral() string { return re.Token.Literal }
+func (re *RangeExpression) String() string {
+    return "(" + re.Start.String() + re.Token.Literal + re.End.String() + ")"
+}
+
+type PipelineExpression struct {
+    Token       token.Token //the |> token
+    Left        Expression
+    Right       Expression //a call, which gets Left as its first argument, or a function to call with Left
+}
+
+func (pe *PipelineExpression) expressionNode(){}
+func (pe *PipelineExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PipelineExpression) String() string {
+    return "(" + pe.Left.String() + " |> " + pe.Right.String() + ")"
+}
+
+type CoalesceExpression struct {
+    Token       token.Token //the ?? token
+    Left        Expression
+    Right       Expression
+}
+
+func (ce *CoalesceExpression) expressionNode(){}
+func (ce *CoalesceExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CoalesceExpression) String() string {
+    return "(" + ce.Left.String() + " ?? " + ce.Right.String() + ")"
+}
+
 type HashLiteral struct {
     Token       token.Token
-    Pairs       map[Expression]Expression
+    Pairs       []HashPair //in source order
+    End         token.Token //the closing } token
+}
+
+type HashPair struct {
+    Key         Expression
+    Value       Expression
 }
 
 func (hl *HashLiteral) expressionNode(){}
@@ -324,8 +618,8 @@ func (hl *HashLiteral) String() string {
     var out bytes.Buffer
 
     pairs := []string{}
-    for key, value := range hl.Pairs {
-        pairs = append(pairs, key.String() + ":" + value.String())
+    for _, pair := range hl.Pairs {
+        pairs = append(pairs, pair.Key.String() + ":" + pair.Value.String())
     }
 
     out.WriteString("{")
@@ -334,3 +628,203 @@ func (hl *HashLiteral) String() string {
 
     return out.String()
 }
+
+type ListComprehension struct {
+    Token       token.Token //the [ token
+    Element     Expression
+    Variables   []*Identifier //one name, or two for key/value and index/element pairs
+    Iterable    Expression
+    Condition   Expression //nil when there is no if clause
+}
+
+func (lc *ListComprehension) expressionNode(){}
+func (lc *ListComprehension) TokenLiteral() string { return lc.Token.Literal }
+func (lc *ListComprehension) String() string {
+    var out bytes.Buffer
+
+    out.WriteString("[")
+    out.WriteString(lc.Element.String())
+    out.WriteString(comprehensionClause(lc.Variables, lc.Iterable, lc.Condition))
+    out.WriteString("]")
+
+    return out.String()
+}
+
+type HashComprehension struct {
+    Token       token.Token //the { token
+    Key         Expression
+    Value       Expression
+    Variables   []*Identifier
+    Iterable    Expression
+    Condition   Expression
+}
+
+func (hc *HashComprehension) expressionNode(){}
+func (hc *HashComprehension) TokenLiteral() string { return hc.Token.Literal }
+func (hc *HashComprehension) String() string {
+    var out bytes.Buffer
+
+    out.WriteString("{")
+    out.WriteString(hc.Key.String() + ":" + hc.Value.String())
+    out.WriteString(comprehensionClause(hc.Variables, hc.Iterable, hc.Condition))
+    out.WriteString("}")
+
+    return out.String()
+}
+
+func comprehensionClause(variables []*Identifier, iterable, condition Expression) string {
+    var out bytes.Buffer
+
+    names := []string{}
+    for _, v := range variables {
+        names = append(names, v.String())
+    }
+
+    out.WriteString(" for ")
+    out.WriteString(strings.Join(names, ", "))
+    out.WriteString(" in ")
+    out.WriteString(iterable.String())
+
+    if condition != nil {
+        out.WriteString(" if ")
+        out.WriteString(condition.String())
+    }
+
+    return out.String()
+}
+
+type ArrayPattern struct {
+    Token       token.Token //the [ token
+    Elements    []Expression
+    Rest        *Identifier //the ...rest element, if any
+}
+
+func (ap *ArrayPattern) expressionNode(){}
+func (ap *ArrayPattern) TokenLiteral() string { return ap.Token.Literal }
+func (ap *ArrayPattern) String() string {
+    elements := []string{}
+    for _, el := range ap.Elements {
+        elements = append(elements, el.String())
+    }
+
+    if ap.Rest != nil {
+        elements = append(elements, "..." + ap.Rest.String())
+    }
+
+    return "[" + strings.Join(elements, ", ") + "]"
+}
+
+type DefaultPattern struct {
+    Token       token.Token //the = token
+    Pattern     Expression
+    Default     Expression
+}
+
+func (dp *DefaultPattern) expressionNode(){}
+func (dp *DefaultPattern) TokenLiteral() string { return dp.Token.Literal }
+func (dp *DefaultPattern) String() string { return dp.Pattern.String() + " = " + dp.Default.String() }
+
+type HashPattern struct {
+    Token       token.Token //the { token
+    Keys        []string
+    Values      []Expression //parallel to Keys
+}
+
+func (hp *HashPattern) expressionNode(){}
+func (hp *HashPattern) TokenLiteral() string { return hp.Token.Literal }
+func (hp *HashPattern) String() string {
+    pairs := []string{}
+    for i, key := range hp.Keys {
+        value := hp.Values[i]
+        if dp, ok := value.(*DefaultPattern); ok {
+            value = dp.Pattern
+        }
+
+        if ident, ok := value.(*Identifier); ok && ident.Value == key {
+            pairs = append(pairs, hp.Values[i].String())
+        } else {
+            pairs = append(pairs, key + ": " + hp.Values[i].String())
+        }
+    }
+
+    return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+type MatchArm struct {
+    Token       token.Token //the first token of the pattern
+    Pattern     Expression
+    Guard       Expression //nil when there is no if clause
+    Body        Expression
+}
+
+func (ma *MatchArm) TokenLiteral() string { return ma.Token.Literal }
+func (ma *MatchArm) String() string {
+    var out bytes.Buffer
+
+    out.WriteString(ma.Pattern.String())
+    if ma.Guard != nil {
+        out.WriteString(" if ")
+        out.WriteString(ma.Guard.String())
+    }
+    out.WriteString(" => ")
+    out.WriteString(ma.Body.String())
+
+    return out.String()
+}
+
+type MatchExpression struct {
+    Token       token.Token //the match token
+    Subject     Expression
+    Arms        []*MatchArm
+    End         token.Token //the closing } token
+}
+
+func (me *MatchExpression) expressionNode(){}
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExpression) String() string {
+    var out bytes.Buffer
+
+    arms := []string{}
+    for _, arm := range me.Arms {
+        arms = append(arms, arm.String())
+    }
+
+    out.WriteString("match (")
+    out.WriteString(me.Subject.String())
+    out.WriteString(") { ")
+    out.WriteString(strings.Join(arms, ", "))
+    out.WriteString(" }")
+
+    return out.String()
+}
+
+// PatternNames returns the names a destructuring pattern binds, in order,
+// skipping the _ wildcard.
+func PatternNames(pattern Expression) []string {
+    switch pattern := pattern.(type) {
+    case *Identifier:
+        if pattern.Value == "_" {
+            return nil
+        }
+        return []string{pattern.Value}
+    case *DefaultPattern:
+        return PatternNames(pattern.Pattern)
+    case *ArrayPattern:
+        names := []string{}
+        for _, el := range pattern.Elements {
+            names = append(names, PatternNames(el)...)
+        }
+        if pattern.Rest != nil {
+            names = append(names, pattern.Rest.Value)
+        }
+        return names
+    case *HashPattern:
+        names := []string{}
+        for _, value := range pattern.Values {
+            names = append(names, PatternNames(value)...)
+        }
+        return names
+    default:
+        return nil
+    }
+}
@@ -0,0 +1,49 @@
+package ast
+
+import "reflect"
+
+// Copy returns a deep copy of node: every node below it is copied too, so
+// the copy can be rewritten, say by Modify, without touching the original.
+func Copy(node Node) Node {
+    if node == nil {
+        return nil
+    }
+
+    copied := reflect.New(reflect.TypeOf(&node).Elem()).Elem()
+    copyValue(copied, reflect.ValueOf(&node).Elem())
+
+    node, _ = copied.Interface().(Node)
+    return node
+}
+
+func copyValue(dst, src reflect.Value) {
+    switch src.Kind() {
+    case reflect.Interface:
+        if src.IsNil() {
+            return
+        }
+        elem := reflect.New(src.Elem().Type()).Elem()
+        copyValue(elem, src.Elem())
+        dst.Set(elem)
+    case reflect.Ptr:
+        if src.IsNil() {
+            return
+        }
+        dst.Set(reflect.New(src.Type().Elem()))
+        copyValue(dst.Elem(), src.Elem())
+    case reflect.Slice:
+        if src.IsNil() {
+            return
+        }
+        dst.Set(reflect.MakeSlice(src.Type(), src.Len(), src.Len()))
+        for i := 0; i < src.Len(); i++ {
+            copyValue(dst.Index(i), src.Index(i))
+        }
+    case reflect.Struct:
+        for i := 0; i < src.NumField(); i++ {
+            copyValue(dst.Field(i), src.Field(i))
+        }
+    default:
+        dst.Set(src)
+    }
+}
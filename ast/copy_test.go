@@ -0,0 +1,34 @@
+package ast
+
+import (
+    "reflect"
+    "testing"
+    "github.com/JakeNorman007/interpreter/token"
+)
+
+func TestCopy(t *testing.T) {
+    ident := &Identifier{Token: token.Token{Type: token.IDENT, Literal: "x"}, Value: "x"}
+    original := &Program{Statements: []Statement{
+        &ExpressionStatement{Expression: &InfixExpression{Left: ident, Operator: "+", Right: &IntegerLiteral{Value: 1}}},
+        &ExpressionStatement{Expression: &HashLiteral{Pairs: []HashPair{{Key: ident, Value: &Boolean{Value: true}}}}},
+    }}
+
+    copied := Copy(original)
+    if !reflect.DeepEqual(copied, original) {
+        t.Fatalf("copy differs.\nwant=%s\ngot=%s", original.String(), copied.String())
+    }
+
+    Modify(copied, func(node Node) Node {
+        if ident, ok := node.(*Identifier); ok {
+            ident.Value = "y"
+        }
+        return node
+    })
+
+    if ident.Value != "x" {
+        t.Errorf("modifying the copy changed the original: %s", original.String())
+    }
+    if Copy(nil) != nil {
+        t.Errorf("expected the copy of nil to be nil")
+    }
+}
@@ -0,0 +1,127 @@
+package ast
+
+import (
+    "bytes"
+    "fmt"
+    "reflect"
+    "strconv"
+    "strings"
+)
+
+// DOT renders node as a Graphviz DOT graph. Each vertex is labelled with
+// the node type and, where it has one, its operator or literal value; edges
+// are labelled with the field that holds the child.
+func DOT(node Node) string {
+    var out bytes.Buffer
+
+    out.WriteString("digraph AST {\n")
+    out.WriteString("    node [shape=box, fontname=\"monospace\"];\n")
+    if !isNilNode(node) {
+        g := &dotGraph{out: &out}
+        g.children(g.vertex(node), "", reflect.ValueOf(node).Elem())
+    }
+    out.WriteString("}\n")
+
+    return out.String()
+}
+
+type dotGraph struct {
+    out     *bytes.Buffer
+    next    int
+}
+
+func (g *dotGraph) vertex(node Node) string {
+    id := fmt.Sprintf("n%d", g.next)
+    g.next++
+
+    label := reflect.TypeOf(node).Elem().Name()
+    if detail := dotDetail(node); detail != "" {
+        label += "\n" + detail
+    }
+    fmt.Fprintf(g.out, "    %s [label=%s];\n", id, dotQuote(label))
+
+    return id
+}
+
+// children adds an edge from parent to every node held in v, a node struct
+// or a plain struct such as HashPair, labelling each edge prefix + field.
+func (g *dotGraph) children(parent, prefix string, v reflect.Value) {
+    for i := 0; i < v.NumField(); i++ {
+        name := prefix + fieldName(v.Type().Field(i).Name)
+        field := v.Field(i)
+
+        if field.Kind() == reflect.Slice {
+            for j := 0; j < field.Len(); j++ {
+                g.child(parent, fmt.Sprintf("%s[%d]", name, j), field.Index(j))
+            }
+            continue
+        }
+        g.child(parent, name, field)
+    }
+}
+
+func (g *dotGraph) child(parent, label string, v reflect.Value) {
+    if v.Kind() == reflect.Struct && v.Type() != tokenType {
+        g.children(parent, label + ".", v)
+        return
+    }
+
+    if (v.Kind() != reflect.Interface && v.Kind() != reflect.Ptr) || v.IsNil() {
+        return
+    }
+
+    node, ok := v.Interface().(Node)
+    if !ok || isNilNode(node) {
+        return
+    }
+
+    id := g.vertex(node)
+    fmt.Fprintf(g.out, "    %s -> %s [label=%s];\n", parent, id, dotQuote(label))
+    g.children(id, "", reflect.ValueOf(node).Elem())
+}
+
+var tokenType = reflect.TypeOf(Identifier{}.Token)
+
+func isNilNode(node Node) bool {
+    if node == nil {
+        return true
+    }
+    v := reflect.ValueOf(node)
+    return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
+func dotDetail(node Node) string {
+    switch n := node.(type) {
+    case *Identifier:
+        return n.Value
+    case *IntegerLiteral:
+        return n.Token.Literal
+    case *Boolean:
+        return n.Token.Literal
+    case *StringLiteral:
+        return strconv.Quote(n.Value)
+    case *PrefixExpression:
+        return n.Operator
+    case *InfixExpression:
+        return n.Operator
+    case *LetStatement:
+        return n.Token.Literal
+    case *FunctionLiteral:
+        return n.Name
+    case *RangeExpression, *PipelineExpression, *CoalesceExpression,
+        *OptionalPropertyExpression, *OptionalIndexExpression:
+        return n.TokenLiteral()
+    case *HashPattern:
+        return "{" + strings.Join(n.Keys, ", ") + "}"
+    }
+
+    return ""
+}
+
+func dotQuote(label string) string {
+    label = strings.ReplaceAll(label, `\`, `\\`)
+    label = strings.ReplaceAll(label, `"`, `\"`)
+    label = strings.ReplaceAll(label, "\n", `\n`)
+
+    return `"` + label + `"`
+}
@@ -0,0 +1,59 @@
+package ast
+
+import (
+    "strings"
+    "testing"
+    "github.com/JakeNorman007/interpreter/token"
+)
+
+func TestDOT(t *testing.T) {
+    //let x = -1 + "a\"b";
+    program := &Program{Statements: []Statement{
+        &LetStatement{
+            Token: token.Token{Type: token.LET, Literal: "let"},
+            Name: ident("x"),
+            Value: &InfixExpression{
+                Operator: "+",
+                Left: &PrefixExpression{Operator: "-", Right: integer(1)},
+                Right: &StringLiteral{Value: `a"b`},
+            },
+        },
+    }}
+
+    expected := `digraph AST {
+    node [shape=box, fontname="monospace"];
+    n0 [label="Program"];
+    n1 [label="LetStatement\nlet"];
+    n0 -> n1 [label="statements[0]"];
+    n2 [label="Identifier\nx"];
+    n1 -> n2 [label="name"];
+    n3 [label="InfixExpression\n+"];
+    n1 -> n3 [label="value"];
+    n4 [label="PrefixExpression\n-"];
+    n3 -> n4 [label="left"];
+    n5 [label="IntegerLiteral\n1"];
+    n4 -> n5 [label="right"];
+    n6 [label="StringLiteral\n\"a\\\"b\""];
+    n3 -> n6 [label="right"];
+}
+`
+
+    if got := DOT(program); got != expected {
+        t.Errorf("wrong DOT output.\nwant=%s\ngot=%s", expected, got)
+    }
+}
+
+func TestDOTHashPairs(t *testing.T) {
+    hash := &HashLiteral{Pairs: []HashPair{{ident("k"), integer(1)}}}
+    got := DOT(hash)
+
+    for _, edge := range []string{`n0 -> n1 [label="pairs[0].key"]`, `n0 -> n2 [label="pairs[0].value"]`} {
+        if !strings.Contains(got, edge) {
+            t.Errorf("expected %s in\n%s", edge, got)
+        }
+    }
+
+    if got := DOT(nil); got != "digraph AST {\n    node [shape=box, fontname=\"monospace\"];\n}\n" {
+        t.Errorf("wrong DOT output for nil node, got=%s", got)
+    }
+}
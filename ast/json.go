@@ -0,0 +1,233 @@
+package ast
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "reflect"
+    "strings"
+)
+
+// Nodes are encoded as JSON objects tagged with their Go type name under
+// "node", followed by every field with its name in lower camel case. Tokens,
+// which carry the source positions, are encoded as
+// {"type", "literal", "line", "column"}. Nil nodes and nil slices are null.
+
+var nodeTypes = map[string]reflect.Type{}
+
+func init() {
+    for _, node := range []Node{
+        &Program{}, &LetStatement{}, &ReturnStatement{}, &ThrowStatement{},
+        &DeferStatement{}, &ImportStatement{}, &ExportStatement{},
+        &ExpressionStatement{}, &FunctionStatement{}, &BlockStatement{},
+        &Identifier{}, &IntegerLiteral{}, &Boolean{}, &StringLiteral{},
+        &PrefixExpression{}, &InfixExpression{}, &IfExpression{},
+        &TryExpression{}, &FunctionLiteral{}, &MacroLiteral{},
+        &CallExpression{}, &SpreadExpression{}, &KeywordArgument{},
+        &ArrayLiteral{}, &HashLiteral{}, &IndexExpression{},
+        &SliceExpression{}, &PropertyExpression{},
+        &OptionalPropertyExpression{}, &OptionalIndexExpression{},
+        &RangeExpression{}, &PipelineExpression{}, &CoalesceExpression{},
+        &ListComprehension{}, &HashComprehension{}, &ArrayPattern{},
+        &DefaultPattern{}, &HashPattern{}, &MatchArm{}, &MatchExpression{},
+    } {
+        t := reflect.TypeOf(node).Elem()
+        nodeTypes[t.Name()] = t
+    }
+}
+
+// MarshalNode encodes node, and everything below it, as JSON.
+func MarshalNode(node Node) ([]byte, error) {
+    var buf bytes.Buffer
+    if err := encodeValue(&buf, reflect.ValueOf(&node).Elem()); err != nil {
+        return nil, err
+    }
+
+    return buf.Bytes(), nil
+}
+
+// UnmarshalNode decodes JSON produced by MarshalNode back into the node it
+// was encoded from.
+func UnmarshalNode(data []byte) (Node, error) {
+    var node Node
+    if err := decodeValue(data, reflect.ValueOf(&node).Elem()); err != nil {
+        return nil, err
+    }
+
+    return node, nil
+}
+
+func (p *Program) MarshalJSON() ([]byte, error) {
+    return MarshalNode(p)
+}
+
+func (p *Program) UnmarshalJSON(data []byte) error {
+    return decodeValue(data, reflect.ValueOf(p).Elem())
+}
+
+func fieldName(name string) string {
+    return strings.ToLower(name[:1]) + name[1:]
+}
+
+func encodeValue(buf *bytes.Buffer, v reflect.Value) error {
+    switch v.Kind() {
+    case reflect.Interface, reflect.Ptr:
+        if v.IsNil() {
+            buf.WriteString("null")
+            return nil
+        }
+        return encodeValue(buf, v.Elem())
+    case reflect.Slice:
+        if v.IsNil() {
+            buf.WriteString("null")
+            return nil
+        }
+        buf.WriteByte('[')
+        for i := 0; i < v.Len(); i++ {
+            if i > 0 {
+                buf.WriteByte(',')
+            }
+            if err := encodeValue(buf, v.Index(i)); err != nil {
+                return err
+            }
+        }
+        buf.WriteByte(']')
+        return nil
+    case reflect.Struct:
+        return encodeStruct(buf, v)
+    case reflect.String, reflect.Bool, reflect.Int, reflect.Int64:
+        data, err := json.Marshal(v.Interface())
+        if err != nil {
+            return err
+        }
+        buf.Write(data)
+        return nil
+    }
+
+    return fmt.Errorf("cannot encode %s as JSON", v.Type())
+}
+
+func encodeStruct(buf *bytes.Buffer, v reflect.Value) error {
+    t := v.Type()
+
+    buf.WriteByte('{')
+    if nodeTypes[t.Name()] == t {
+        fmt.Fprintf(buf, `"node":%q`, t.Name())
+        if t.NumField() > 0 {
+            buf.WriteByte(',')
+        }
+    }
+
+    for i := 0; i < t.NumField(); i++ {
+        if i > 0 {
+            buf.WriteByte(',')
+        }
+        fmt.Fprintf(buf, "%q:", fieldName(t.Field(i).Name))
+        if err := encodeValue(buf, v.Field(i)); err != nil {
+            return err
+        }
+    }
+    buf.WriteByte('}')
+
+    return nil
+}
+
+func decodeValue(data []byte, v reflect.Value) error {
+    if string(bytes.TrimSpace(data)) == "null" {
+        v.Set(reflect.Zero(v.Type()))
+        return nil
+    }
+
+    switch v.Kind() {
+    case reflect.Interface:
+        return decodeNode(data, v)
+    case reflect.Ptr:
+        elem := reflect.New(v.Type().Elem())
+        if err := decodeValue(data, elem.Elem()); err != nil {
+            return err
+        }
+        v.Set(elem)
+        return nil
+    case reflect.Slice:
+        var items []json.RawMessage
+        if err := json.Unmarshal(data, &items); err != nil {
+            return err
+        }
+        slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+        for i, item := range items {
+            if err := decodeValue(item, slice.Index(i)); err != nil {
+                return err
+            }
+        }
+        v.Set(slice)
+        return nil
+    case reflect.Struct:
+        return decodeStruct(data, v)
+    }
+
+    return json.Unmarshal(data, v.Addr().Interface())
+}
+
+// decodeNode decodes a tagged node into v, an Expression, Statement or Node.
+func decodeNode(data []byte, v reflect.Value) error {
+    var tag struct {
+        Node string `json:"node"`
+    }
+    if err := json.Unmarshal(data, &tag); err != nil {
+        return err
+    }
+
+    t, ok := nodeTypes[tag.Node]
+    if !ok {
+        return fmt.Errorf("unknown node type %q", tag.Node)
+    }
+
+    node := reflect.New(t)
+    if !node.Type().Implements(v.Type()) {
+        return fmt.Errorf("%s is not %s", tag.Node, v.Type().Name())
+    }
+
+    if err := decodeStruct(data, node.Elem()); err != nil {
+        return err
+    }
+    v.Set(node)
+
+    return nil
+}
+
+func decodeStruct(data []byte, v reflect.Value) error {
+    var fields map[string]json.RawMessage
+    if err := json.Unmarshal(data, &fields); err != nil {
+        return err
+    }
+
+    t := v.Type()
+    if tag, ok := fields["node"]; ok {
+        var name string
+        if err := json.Unmarshal(tag, &name); err != nil {
+            return err
+        }
+        if name != t.Name() {
+            return fmt.Errorf("expected %s node, got %s", t.Name(), name)
+        }
+        delete(fields, "node")
+    }
+
+    for i := 0; i < t.NumField(); i++ {
+        name := fieldName(t.Field(i).Name)
+        raw, ok := fields[name]
+        if !ok {
+            continue
+        }
+        if err := decodeValue(raw, v.Field(i)); err != nil {
+            return fmt.Errorf("%s.%s: %s", t.Name(), name, err)
+        }
+        delete(fields, name)
+    }
+
+    for name := range fields {
+        return fmt.Errorf("unknown field %q in %s", name, t.Name())
+    }
+
+    return nil
+}
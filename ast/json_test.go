@@ -0,0 +1,76 @@
+package ast
+
+import (
+    "encoding/json"
+    "reflect"
+    "strings"
+    "testing"
+    "github.com/JakeNorman007/interpreter/token"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+    //let f = func(a, b = 9007199254740993) { {"k": a, b: []} }
+    program := &Program{Statements: []Statement{
+        &LetStatement{
+            Token: token.Token{Type: token.LET, Literal: "let", Line: 1, Column: 1},
+            Name: ident("f"),
+            Value: &FunctionLiteral{
+                Parameters: []*Identifier{ident("a"), ident("b")},
+                Defaults: []Expression{nil, integer(9007199254740993)},
+                Body: &BlockStatement{
+                    Statements: []Statement{&ExpressionStatement{Expression: &HashLiteral{Pairs: []HashPair{
+                        {&StringLiteral{Value: "k"}, ident("a")},
+                        {ident("b"), &ArrayLiteral{Elements: []Expression{}}},
+                    }}}},
+                    End: token.Token{Type: token.RIGHTBRACE, Literal: "}", Line: 3, Column: 7},
+                },
+            },
+        },
+    }}
+
+    data, err := json.Marshal(program)
+    if err != nil {
+        t.Fatalf("marshal failed: %s", err)
+    }
+
+    decoded := &Program{}
+    if err := json.Unmarshal(data, decoded); err != nil {
+        t.Fatalf("unmarshal failed: %s", err)
+    }
+
+    if !reflect.DeepEqual(decoded, program) {
+        t.Errorf("round trip changed the program.\nwant=%#v\ngot=%#v", program, decoded)
+    }
+
+    for _, want := range []string{
+        `"node":"LetStatement"`,
+        `"token":{"type":"LET","literal":"let","line":1,"column":1}`,
+        `"defaults":[null,{"node":"IntegerLiteral"`,
+        `"pairs":[{"key":{"node":"StringLiteral"`,
+        `"elements":[]`,
+    } {
+        if !strings.Contains(string(data), want) {
+            t.Errorf("expected %s in %s", want, data)
+        }
+    }
+}
+
+func TestUnmarshalNodeErrors(t *testing.T) {
+    tests := []struct {
+        input       string
+        expected    string
+    }{
+        {`{"node": "Nope"}`, `unknown node type "Nope"`},
+        {`{"node": "Program", "statements": [{"node": "Identifier"}]}`, "Program.statements: Identifier is not Statement"},
+        {`{"node": "Identifier", "name": "x"}`, `unknown field "name" in Identifier`},
+        {`{"node": "LetStatement", "name": {"node": "Boolean"}}`, "LetStatement.name: expected Identifier node, got Boolean"},
+        {`{"node": "IntegerLiteral", "value": "1"}`, "IntegerLiteral.value: json: cannot unmarshal string"},
+    }
+
+    for _, tt := range tests {
+        _, err := UnmarshalNode([]byte(tt.input))
+        if err == nil || !strings.HasPrefix(err.Error(), tt.expected) {
+            t.Errorf("%s: expected error %q, got %v", tt.input, tt.expected, err)
+        }
+    }
+}
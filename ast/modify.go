@@ -0,0 +1,191 @@
+package ast
+
+type ModifierFunc func(Node) Node
+
+// Modify rewrites node bottom-up and in place: every child is modified first,
+// then modifier is applied to node itself and its result returned. Nil
+// children are skipped. A child replaced by a node of the wrong kind for its
+// field (say, a statement where an identifier belongs) becomes nil.
+func Modify(node Node, modifier ModifierFunc) Node {
+    switch node := node.(type) {
+    case *Program:
+        modifyStatements(node.Statements, modifier)
+    case *BlockStatement:
+        modifyStatements(node.Statements, modifier)
+    case *LetStatement:
+        node.Name = modifyIdentifier(node.Name, modifier)
+        node.Pattern = modifyExpression(node.Pattern, modifier)
+        node.Value = modifyExpression(node.Value, modifier)
+    case *ReturnStatement:
+        node.ReturnValue = modifyExpression(node.ReturnValue, modifier)
+    case *ThrowStatement:
+        node.Value = modifyExpression(node.Value, modifier)
+    case *DeferStatement:
+        node.Call = modifyExpression(node.Call, modifier)
+    case *ImportStatement:
+        if node.Path != nil {
+            node.Path, _ = Modify(node.Path, modifier).(*StringLiteral)
+        }
+        node.Alias = modifyIdentifier(node.Alias, modifier)
+    case *ExportStatement:
+        if node.Statement != nil {
+            node.Statement, _ = Modify(node.Statement, modifier).(Statement)
+        }
+    case *ExpressionStatement:
+        node.Expression = modifyExpression(node.Expression, modifier)
+    case *FunctionStatement:
+        node.Name = modifyIdentifier(node.Name, modifier)
+        if node.Function != nil {
+            node.Function, _ = Modify(node.Function, modifier).(*FunctionLiteral)
+        }
+
+    case *PrefixExpression:
+        node.Right = modifyExpression(node.Right, modifier)
+    case *InfixExpression:
+        node.Left = modifyExpression(node.Left, modifier)
+        node.Right = modifyExpression(node.Right, modifier)
+    case *IfExpression:
+        node.Condition = modifyExpression(node.Condition, modifier)
+        node.Consequence = modifyBlock(node.Consequence, modifier)
+        node.Alternative = modifyBlock(node.Alternative, modifier)
+    case *TryExpression:
+        node.Block = modifyBlock(node.Block, modifier)
+        node.Param = modifyIdentifier(node.Param, modifier)
+        node.Catch = modifyBlock(node.Catch, modifier)
+        node.Finally = modifyBlock(node.Finally, modifier)
+    case *FunctionLiteral:
+        for i, param := range node.Parameters {
+            node.Parameters[i] = modifyIdentifier(param, modifier)
+            if i < len(node.Defaults) {
+                node.Defaults[i] = modifyExpression(node.Defaults[i], modifier)
+            }
+        }
+        node.Rest = modifyIdentifier(node.Rest, modifier)
+        node.Body = modifyBlock(node.Body, modifier)
+    case *MacroLiteral:
+        for i, param := range node.Parameters {
+            node.Parameters[i] = modifyIdentifier(param, modifier)
+        }
+        node.Body = modifyBlock(node.Body, modifier)
+    case *CallExpression:
+        node.Function = modifyExpression(node.Function, modifier)
+        modifyExpressions(node.Arguments, modifier)
+    case *SpreadExpression:
+        node.Value = modifyExpression(node.Value, modifier)
+    case *KeywordArgument:
+        node.Name = modifyIdentifier(node.Name, modifier)
+        node.Value = modifyExpression(node.Value, modifier)
+    case *ArrayLiteral:
+        modifyExpressions(node.Elements, modifier)
+    case *HashLiteral:
+        for i, pair := range node.Pairs {
+            node.Pairs[i].Key = modifyExpression(pair.Key, modifier)
+            node.Pairs[i].Value = modifyExpression(pair.Value, modifier)
+        }
+    case *IndexExpression:
+        node.Left = modifyExpression(node.Left, modifier)
+        node.Index = modifyExpression(node.Index, modifier)
+    case *OptionalIndexExpression:
+        node.Left = modifyExpression(node.Left, modifier)
+        node.Index = modifyExpression(node.Index, modifier)
+    case *SliceExpression:
+        node.Left = modifyExpression(node.Left, modifier)
+        node.Start = modifyExpression(node.Start, modifier)
+        node.End = modifyExpression(node.End, modifier)
+        node.Step = modifyExpression(node.Step, modifier)
+    case *PropertyExpression:
+        node.Object = modifyExpression(node.Object, modifier)
+        node.Property = modifyIdentifier(node.Property, modifier)
+    case *OptionalPropertyExpression:
+        node.Object = modifyExpression(node.Object, modifier)
+        node.Property = modifyIdentifier(node.Property, modifier)
+    case *RangeExpression:
+        node.Start = modifyExpression(node.Start, modifier)
+        node.End = modifyExpression(node.End, modifier)
+    case *PipelineExpression:
+        node.Left = modifyExpression(node.Left, modifier)
+        node.Right = modifyExpression(node.Right, modifier)
+    case *CoalesceExpression:
+        node.Left = modifyExpression(node.Left, modifier)
+        node.Right = modifyExpression(node.Right, modifier)
+    case *ListComprehension:
+        node.Element = modifyExpression(node.Element, modifier)
+        modifyIdentifiers(node.Variables, modifier)
+        node.Iterable = modifyExpression(node.Iterable, modifier)
+        node.Condition = modifyExpression(node.Condition, modifier)
+    case *HashComprehension:
+        node.Key = modifyExpression(node.Key, modifier)
+        node.Value = modifyExpression(node.Value, modifier)
+        modifyIdentifiers(node.Variables, modifier)
+        node.Iterable = modifyExpression(node.Iterable, modifier)
+        node.Condition = modifyExpression(node.Condition, modifier)
+    case *ArrayPattern:
+        modifyExpressions(node.Elements, modifier)
+        node.Rest = modifyIdentifier(node.Rest, modifier)
+    case *HashPattern:
+        modifyExpressions(node.Values, modifier)
+    case *DefaultPattern:
+        node.Pattern = modifyExpression(node.Pattern, modifier)
+        node.Default = modifyExpression(node.Default, modifier)
+    case *MatchExpression:
+        node.Subject = modifyExpression(node.Subject, modifier)
+        for i, arm := range node.Arms {
+            if arm != nil {
+                node.Arms[i], _ = Modify(arm, modifier).(*MatchArm)
+            }
+        }
+    case *MatchArm:
+        node.Pattern = modifyExpression(node.Pattern, modifier)
+        node.Guard = modifyExpression(node.Guard, modifier)
+        node.Body = modifyExpression(node.Body, modifier)
+    }
+
+    return modifier(node)
+}
+
+func modifyStatements(statements []Statement, modifier ModifierFunc) {
+    for i, statement := range statements {
+        if statement != nil {
+            statements[i], _ = Modify(statement, modifier).(Statement)
+        }
+    }
+}
+
+func modifyExpressions(exps []Expression, modifier ModifierFunc) {
+    for i, exp := range exps {
+        exps[i] = modifyExpression(exp, modifier)
+    }
+}
+
+func modifyIdentifiers(idents []*Identifier, modifier ModifierFunc) {
+    for i, ident := range idents {
+        idents[i] = modifyIdentifier(ident, modifier)
+    }
+}
+
+func modifyExpression(exp Expression, modifier ModifierFunc) Expression {
+    if exp == nil {
+        return nil
+    }
+
+    modified, _ := Modify(exp, modifier).(Expression)
+    return modified
+}
+
+func modifyIdentifier(ident *Identifier, modifier ModifierFunc) *Identifier {
+    if ident == nil {
+        return nil
+    }
+
+    modified, _ := Modify(ident, modifier).(*Identifier)
+    return modified
+}
+
+func modifyBlock(block *BlockStatement, modifier ModifierFunc) *BlockStatement {
+    if block == nil {
+        return nil
+    }
+
+    modified, _ := Modify(block, modifier).(*BlockStatement)
+    return modified
+}
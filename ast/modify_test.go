@@ -0,0 +1,136 @@
+package ast
+
+import (
+    "reflect"
+    "testing"
+    "github.com/JakeNorman007/interpreter/token"
+)
+
+func TestModify(t *testing.T) {
+    one := func() Expression { return &IntegerLiteral{Value: 1} }
+    two := func() Expression { return &IntegerLiteral{Value: 2} }
+
+    turnOneIntoTwo := func(node Node) Node {
+        integer, ok := node.(*IntegerLiteral)
+        if !ok {
+            return node
+        }
+
+        if integer.Value != 1 {
+            return node
+        }
+
+        integer.Value = 2
+        return integer
+    }
+
+    tests := []struct {
+        input       Node
+        expected    Node
+    }{
+        {one(), two()},
+        {
+            &Program{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+            &Program{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+        },
+        {
+            &InfixExpression{Left: one(), Operator: "+", Right: two()},
+            &InfixExpression{Left: two(), Operator: "+", Right: two()},
+        },
+        {
+            &PrefixExpression{Operator: "-", Right: one()},
+            &PrefixExpression{Operator: "-", Right: two()},
+        },
+        {
+            &IndexExpression{Left: one(), Index: one()},
+            &IndexExpression{Left: two(), Index: two()},
+        },
+        {
+            &IfExpression{
+                Condition: one(),
+                Consequence: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+                Alternative: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+            },
+            &IfExpression{
+                Condition: two(),
+                Consequence: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+                Alternative: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+            },
+        },
+        {&ReturnStatement{ReturnValue: one()}, &ReturnStatement{ReturnValue: two()}},
+        {&LetStatement{Value: one()}, &LetStatement{Value: two()}},
+        {
+            &FunctionLiteral{
+                Parameters: []*Identifier{},
+                Body: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+            },
+            &FunctionLiteral{
+                Parameters: []*Identifier{},
+                Body: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+            },
+        },
+        {
+            &CallExpression{Function: &Identifier{Value: "f"}, Arguments: []Expression{one(), two()}},
+            &CallExpression{Function: &Identifier{Value: "f"}, Arguments: []Expression{two(), two()}},
+        },
+        {&ArrayLiteral{Elements: []Expression{one(), one()}}, &ArrayLiteral{Elements: []Expression{two(), two()}}},
+    }
+
+    for _, tt := range tests {
+        modified := Modify(tt.input, turnOneIntoTwo)
+
+        if !reflect.DeepEqual(modified, tt.expected) {
+            t.Errorf("not equal. got=%#v, want=%#v", modified, tt.expected)
+        }
+    }
+
+    hashLiteral := &HashLiteral{Pairs: []HashPair{{one(), one()}, {two(), one()}}}
+    Modify(hashLiteral, turnOneIntoTwo)
+
+    for _, pair := range hashLiteral.Pairs {
+        if pair.Key.(*IntegerLiteral).Value != 2 || pair.Value.(*IntegerLiteral).Value != 2 {
+            t.Errorf("hash pair not modified: %s: %s", pair.Key, pair.Value)
+        }
+    }
+}
+
+func TestModifyCoversEveryNode(t *testing.T) {
+    rename := func(node Node) Node {
+        if id, ok := node.(*Identifier); ok && id.Value == "x" {
+            return &Identifier{Value: "y"}
+        }
+        return node
+    }
+
+    tests := []struct {
+        input       Node
+        expected    string
+    }{
+        {
+            &FunctionLiteral{
+                Token: token.Token{Type: token.FUNCTION, Literal: "func"},
+                Parameters: []*Identifier{ident("x")},
+                Defaults: []Expression{ident("x")},
+                Rest: ident("x"),
+                Body: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: ident("x")}}},
+            },
+            "func(y = y, ...y) y",
+        },
+        {&HashLiteral{Pairs: []HashPair{{ident("x"), ident("x")}}}, "{y:y}"},
+        {&SliceExpression{Left: ident("x"), Step: ident("x")}, "(y[::y])"},
+        {&RangeExpression{Token: token.Token{Type: token.RANGE, Literal: ".."}, Start: ident("x"), End: ident("x"), Inclusive: true}, "(y..y)"},
+        {
+            &ListComprehension{Element: ident("x"), Variables: []*Identifier{ident("x")}, Iterable: ident("x")},
+            "[y for y in y]",
+        },
+        {&PropertyExpression{Object: ident("x"), Property: ident("x")}, "(y.y)"},
+    }
+
+    for _, tt := range tests {
+        modified := Modify(tt.input, rename)
+
+        if modified.String() != tt.expected {
+            t.Errorf("not modified everywhere. want=%q, got=%q", tt.expected, modified.String())
+        }
+    }
+}
@@ -0,0 +1,186 @@
+package ast
+
+// A Visitor's Visit method is invoked for each node encountered by Walk. If
+// the result w is not nil, Walk visits each of the children of node with w,
+// followed by a call of w.Visit(nil).
+type Visitor interface {
+    Visit(node Node) (w Visitor)
+}
+
+// Walk traverses an AST in depth-first order, children in source order. It
+// starts by calling v.Visit(node); node must not be nil.
+func Walk(v Visitor, node Node) {
+    if v = v.Visit(node); v == nil {
+        return
+    }
+
+    switch n := node.(type) {
+    case *Program:
+        walkStatements(v, n.Statements)
+    case *BlockStatement:
+        walkStatements(v, n.Statements)
+    case *LetStatement:
+        walkIdentifier(v, n.Name)
+        walkExpressions(v, n.Pattern, n.Value)
+    case *ReturnStatement:
+        walkExpressions(v, n.ReturnValue)
+    case *ThrowStatement:
+        walkExpressions(v, n.Value)
+    case *DeferStatement:
+        walkExpressions(v, n.Call)
+    case *ImportStatement:
+        if n.Path != nil {
+            Walk(v, n.Path)
+        }
+        walkIdentifier(v, n.Alias)
+    case *ExportStatement:
+        walkStatements(v, []Statement{n.Statement})
+    case *ExpressionStatement:
+        walkExpressions(v, n.Expression)
+    case *FunctionStatement:
+        walkIdentifier(v, n.Name)
+        if n.Function != nil {
+            Walk(v, n.Function)
+        }
+
+    case *Identifier, *IntegerLiteral, *Boolean, *StringLiteral:
+        //leaves
+
+    case *PrefixExpression:
+        walkExpressions(v, n.Right)
+    case *InfixExpression:
+        walkExpressions(v, n.Left, n.Right)
+    case *IfExpression:
+        walkExpressions(v, n.Condition)
+        walkBlock(v, n.Consequence)
+        walkBlock(v, n.Alternative)
+    case *TryExpression:
+        walkBlock(v, n.Block)
+        walkIdentifier(v, n.Param)
+        walkBlock(v, n.Catch)
+        walkBlock(v, n.Finally)
+    case *FunctionLiteral:
+        for i, param := range n.Parameters {
+            walkIdentifier(v, param)
+            if i < len(n.Defaults) {
+                walkExpressions(v, n.Defaults[i])
+            }
+        }
+        walkIdentifier(v, n.Rest)
+        walkBlock(v, n.Body)
+    case *MacroLiteral:
+        for _, param := range n.Parameters {
+            walkIdentifier(v, param)
+        }
+        walkBlock(v, n.Body)
+    case *CallExpression:
+        walkExpressions(v, n.Function)
+        walkExpressions(v, n.Arguments...)
+    case *SpreadExpression:
+        walkExpressions(v, n.Value)
+    case *KeywordArgument:
+        walkIdentifier(v, n.Name)
+        walkExpressions(v, n.Value)
+    case *ArrayLiteral:
+        walkExpressions(v, n.Elements...)
+    case *HashLiteral:
+        for _, pair := range n.Pairs {
+            walkExpressions(v, pair.Key, pair.Value)
+        }
+    case *IndexExpression:
+        walkExpressions(v, n.Left, n.Index)
+    case *OptionalIndexExpression:
+        walkExpressions(v, n.Left, n.Index)
+    case *SliceExpression:
+        walkExpressions(v, n.Left, n.Start, n.End, n.Step)
+    case *PropertyExpression:
+        walkExpressions(v, n.Object)
+        walkIdentifier(v, n.Property)
+    case *OptionalPropertyExpression:
+        walkExpressions(v, n.Object)
+        walkIdentifier(v, n.Property)
+    case *RangeExpression:
+        walkExpressions(v, n.Start, n.End)
+    case *PipelineExpression:
+        walkExpressions(v, n.Left, n.Right)
+    case *CoalesceExpression:
+        walkExpressions(v, n.Left, n.Right)
+    case *ListComprehension:
+        walkExpressions(v, n.Element)
+        walkComprehension(v, n.Variables, n.Iterable, n.Condition)
+    case *HashComprehension:
+        walkExpressions(v, n.Key, n.Value)
+        walkComprehension(v, n.Variables, n.Iterable, n.Condition)
+    case *ArrayPattern:
+        walkExpressions(v, n.Elements...)
+        walkIdentifier(v, n.Rest)
+    case *HashPattern:
+        walkExpressions(v, n.Values...)
+    case *DefaultPattern:
+        walkExpressions(v, n.Pattern, n.Default)
+    case *MatchExpression:
+        walkExpressions(v, n.Subject)
+        for _, arm := range n.Arms {
+            if arm != nil {
+                Walk(v, arm)
+            }
+        }
+    case *MatchArm:
+        walkExpressions(v, n.Pattern, n.Guard, n.Body)
+    }
+
+    v.Visit(nil)
+}
+
+func walkStatements(v Visitor, statements []Statement) {
+    for _, statement := range statements {
+        if statement != nil {
+            Walk(v, statement)
+        }
+    }
+}
+
+//walks each non-nil expression in turn
+func walkExpressions(v Visitor, exps ...Expression) {
+    for _, exp := range exps {
+        if exp != nil {
+            Walk(v, exp)
+        }
+    }
+}
+
+func walkIdentifier(v Visitor, ident *Identifier) {
+    if ident != nil {
+        Walk(v, ident)
+    }
+}
+
+func walkBlock(v Visitor, block *BlockStatement) {
+    if block != nil {
+        Walk(v, block)
+    }
+}
+
+func walkComprehension(v Visitor, variables []*Identifier, iterable, condition Expression) {
+    for _, variable := range variables {
+        walkIdentifier(v, variable)
+    }
+    walkExpressions(v, iterable, condition)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+    if f(node) {
+        return f
+    }
+
+    return nil
+}
+
+// Inspect traverses an AST in depth-first order, calling f(node) for each
+// node. If f returns true, Inspect continues into the children of node,
+// followed by a call of f(nil).
+func Inspect(node Node, f func(Node) bool) {
+    Walk(inspector(f), node)
+}
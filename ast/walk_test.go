@@ -0,0 +1,121 @@
+package ast
+
+import (
+    "fmt"
+    "reflect"
+    "testing"
+    "github.com/JakeNorman007/interpreter/token"
+)
+
+func ident(name string) *Identifier { return &Identifier{Value: name} }
+func integer(value int64) *IntegerLiteral {
+    return &IntegerLiteral{Token: token.Token{Type: token.INT, Literal: fmt.Sprint(value)}, Value: value}
+}
+
+func TestInspect(t *testing.T) {
+    //func(a, b = 1) { if (a) { b } else { [a, 2] } }
+    fn := &FunctionLiteral{
+        Parameters: []*Identifier{ident("a"), ident("b")},
+        Defaults: []Expression{nil, integer(1)},
+        Body: &BlockStatement{Statements: []Statement{
+            &ExpressionStatement{Expression: &IfExpression{
+                Condition: ident("a"),
+                Consequence: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: ident("b")}}},
+                Alternative: &BlockStatement{Statements: []Statement{
+                    &ExpressionStatement{Expression: &ArrayLiteral{Elements: []Expression{ident("a"), integer(2)}}},
+                }},
+            }},
+        }},
+    }
+
+    var visited []string
+    Inspect(fn, func(node Node) bool {
+        switch node := node.(type) {
+        case *Identifier:
+            visited = append(visited, node.Value)
+        case *IntegerLiteral:
+            visited = append(visited, node.String())
+        }
+        return true
+    })
+
+    expected := []string{"a", "b", "1", "a", "b", "a", "2"}
+    if !reflect.DeepEqual(visited, expected) {
+        t.Errorf("wrong visit order. want=%v, got=%v", expected, visited)
+    }
+
+    //returning false skips the children of a node
+    blocks := 0
+    Inspect(fn, func(node Node) bool {
+        if _, ok := node.(*BlockStatement); ok {
+            blocks++
+            return false
+        }
+        return true
+    })
+
+    if blocks != 1 {
+        t.Errorf("expected only the function body block to be visited, got %d blocks", blocks)
+    }
+}
+
+type countingVisitor struct {
+    enter   int
+    leave   int
+}
+
+func (c *countingVisitor) Visit(node Node) Visitor {
+    if node == nil {
+        c.leave++
+    } else {
+        c.enter++
+    }
+    return c
+}
+
+func TestWalk(t *testing.T) {
+    tests := []struct {
+        node        Node
+        nodes       int
+    }{
+        {&HashLiteral{Pairs: []HashPair{{ident("k"), integer(1)}, {ident("j"), integer(2)}}}, 5},
+        {&SliceExpression{Left: ident("xs"), End: integer(2)}, 3},
+        {&LetStatement{Pattern: &ArrayPattern{Elements: []Expression{ident("a")}, Rest: ident("r")}, Value: ident("xs")}, 5},
+        {&TryExpression{Block: &BlockStatement{}, Param: ident("e"), Catch: &BlockStatement{}}, 4},
+        {
+            &MatchExpression{Subject: ident("x"), Arms: []*MatchArm{
+                {Pattern: integer(1), Guard: ident("ok"), Body: integer(2)},
+            }},
+            6,
+        },
+        {&ListComprehension{Element: ident("x"), Variables: []*Identifier{ident("x")}, Iterable: ident("xs")}, 4},
+    }
+
+    for _, tt := range tests {
+        v := &countingVisitor{}
+        Walk(v, tt.node)
+
+        if v.enter != tt.nodes || v.leave != tt.nodes {
+            t.Errorf("%s: expected %d nodes entered and left, got %d and %d", tt.node, tt.nodes, v.enter, v.leave)
+        }
+    }
+}
+
+func TestHashLiteralWalkOrder(t *testing.T) {
+    hash := &HashLiteral{}
+    for _, name := range []string{"d", "b", "a", "c"} {
+        hash.Pairs = append(hash.Pairs, HashPair{ident(name), integer(0)})
+    }
+
+    var keys []string
+    Inspect(hash, func(node Node) bool {
+        if id, ok := node.(*Identifier); ok {
+            keys = append(keys, id.Value)
+        }
+        return true
+    })
+
+    if !reflect.DeepEqual(keys, []string{"d", "b", "a", "c"}) {
+        t.Errorf("hash keys not visited in source order: %v", keys)
+    }
+}
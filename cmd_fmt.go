@@ -0,0 +1,202 @@
+package main
+
+import (
+    "bytes"
+    "flag"
+    "fmt"
+    "io"
+    "os"
+    "strings"
+    "github.com/JakeNorman007/interpreter/format"
+)
+
+// runFmt implements `elliott fmt [-w] [-d] files...`. With no files it
+// formats standard input. It returns the exit status: 1 if a file failed to
+// parse, or if -d found a file that is not formatted.
+func runFmt(args []string) int {
+    flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+    write := flags.Bool("w", false, "write the result back to the source file instead of stdout")
+    diff := flags.Bool("d", false, "print a diff of the changes instead of the formatted source")
+    flags.Usage = func() {
+        fmt.Fprintln(flags.Output(), "usage: elliott fmt [-w] [-d] [files...]")
+        flags.PrintDefaults()
+    }
+    flags.Parse(args)
+
+    if flags.NArg() == 0 {
+        src, err := io.ReadAll(os.Stdin)
+        if err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            return 1
+        }
+        return formatSource("<stdin>", src, *write, *diff, false)
+    }
+
+    status := 0
+    for _, path := range flags.Args() {
+        src, err := os.ReadFile(path)
+        if err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            status = 1
+            continue
+        }
+
+        if s := formatSource(path, src, *write, *diff, true); s != 0 {
+            status = s
+        }
+    }
+
+    return status
+}
+
+func formatSource(name string, src []byte, write, diff, isFile bool) int {
+    out, err := format.Source(src)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+        return 1
+    }
+
+    status := 0
+    changed := !bytes.Equal(src, out)
+
+    if diff && changed {
+        fmt.Print(unifiedDiff(name, string(src), string(out)))
+        status = 1
+    }
+
+    if write && isFile {
+        if changed {
+            info, err := os.Stat(name)
+            if err != nil {
+                fmt.Fprintln(os.Stderr, err)
+                return 1
+            }
+            if err := os.WriteFile(name, out, info.Mode().Perm()); err != nil {
+                fmt.Fprintln(os.Stderr, err)
+                return 1
+            }
+        }
+        return status
+    }
+
+    if !diff {
+        os.Stdout.Write(out)
+    }
+
+    return status
+}
+
+const diffContext = 3
+
+type diffLine struct {
+    op      byte //' ', '-' or '+'
+    text    string
+    a, b    int //0-based line numbers in the old and new text
+}
+
+// unifiedDiff returns a unified diff turning a into b, or "" if they match.
+func unifiedDiff(name, a, b string) string {
+    lines := diffLines(splitLines(a), splitLines(b))
+
+    var out strings.Builder
+    for start := 0; start < len(lines); {
+        for start < len(lines) && lines[start].op == ' ' {
+            start++
+        }
+        if start == len(lines) {
+            break
+        }
+
+        //extend the hunk until a run of unchanged lines long enough to split on
+        end := start
+        for end < len(lines) {
+            if lines[end].op != ' ' {
+                end++
+                continue
+            }
+            run := end
+            for run < len(lines) && lines[run].op == ' ' {
+                run++
+            }
+            if run == len(lines) || run - end > 2 * diffContext {
+                break
+            }
+            end = run
+        }
+
+        from := max(start - diffContext, 0)
+        to := min(end + diffContext, len(lines))
+
+        if out.Len() == 0 {
+            fmt.Fprintf(&out, "--- %s\n+++ %s (formatted)\n", name, name)
+        }
+        out.WriteString(hunkHeader(lines[from:to]))
+        for _, l := range lines[from:to] {
+            out.WriteString(string(l.op) + l.text + "\n")
+        }
+
+        start = to
+    }
+
+    return out.String()
+}
+
+func hunkHeader(lines []diffLine) string {
+    aStart, bStart := lines[0].a, lines[0].b
+    aLen, bLen := 0, 0
+
+    for _, l := range lines {
+        if l.op != '+' {
+            aLen++
+        }
+        if l.op != '-' {
+            bLen++
+        }
+    }
+
+    return fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", aStart + 1, aLen, bStart + 1, bLen)
+}
+
+//an edit script from the longest common subsequence of a and b
+func diffLines(a, b []string) []diffLine {
+    lcs := make([][]int, len(a) + 1)
+    for i := range lcs {
+        lcs[i] = make([]int, len(b) + 1)
+    }
+    for i := len(a) - 1; i >= 0; i-- {
+        for j := len(b) - 1; j >= 0; j-- {
+            if a[i] == b[j] {
+                lcs[i][j] = lcs[i + 1][j + 1] + 1
+            } else {
+                lcs[i][j] = max(lcs[i + 1][j], lcs[i][j + 1])
+            }
+        }
+    }
+
+    lines := []diffLine{}
+    i, j := 0, 0
+    for i < len(a) || j < len(b) {
+        switch {
+        case i < len(a) && j < len(b) && a[i] == b[j]:
+            lines = append(lines, diffLine{' ', a[i], i, j})
+            i++
+            j++
+        case i < len(a) && (j == len(b) || lcs[i + 1][j] >= lcs[i][j + 1]):
+            lines = append(lines, diffLine{'-', a[i], i, j})
+            i++
+        default:
+            lines = append(lines, diffLine{'+', b[j], i, j})
+            j++
+        }
+    }
+
+    return lines
+}
+
+func splitLines(s string) []string {
+    if s == "" {
+        return nil
+    }
+
+    return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
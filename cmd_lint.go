@@ -0,0 +1,84 @@
+package main
+
+import (
+    "encoding/json"
+    "flag"
+    "fmt"
+    "io"
+    "os"
+    "strings"
+    "github.com/JakeNorman007/interpreter/lint"
+)
+
+type lintFinding struct {
+    File    string  `json:"file"`
+    lint.Finding
+}
+
+// runLint implements `elliott lint [-json] files...`. With no files it lints
+// standard input. It returns 1 if there were findings or a file failed to
+// parse.
+func runLint(args []string) int {
+    flags := flag.NewFlagSet("lint", flag.ExitOnError)
+    asJSON := flags.Bool("json", false, "print the findings as a JSON array")
+    flags.Usage = func() {
+        fmt.Fprintln(flags.Output(), "usage: elliott lint [-json] [files...]")
+        flags.PrintDefaults()
+    }
+    flags.Parse(args)
+
+    paths := flags.Args()
+    if len(paths) == 0 {
+        paths = []string{"<stdin>"}
+    }
+
+    status := 0
+    findings := []lintFinding{}
+    for _, path := range paths {
+        var src []byte
+        var err error
+        if path == "<stdin>" {
+            src, err = io.ReadAll(os.Stdin)
+        } else {
+            src, err = os.ReadFile(path)
+        }
+        if err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            status = 1
+            continue
+        }
+
+        found, err := lint.Source(src)
+        if err != nil {
+            for _, msg := range strings.Split(err.Error(), "\n") {
+                fmt.Fprintf(os.Stderr, "%s: %s\n", path, msg)
+            }
+            status = 1
+            continue
+        }
+
+        for _, f := range found {
+            findings = append(findings, lintFinding{File: path, Finding: f})
+        }
+    }
+
+    if len(findings) > 0 {
+        status = 1
+    }
+
+    if *asJSON {
+        data, err := json.MarshalIndent(findings, "", "  ")
+        if err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            return 1
+        }
+        fmt.Println(string(data))
+        return status
+    }
+
+    for _, f := range findings {
+        fmt.Printf("%s:%s\n", f.File, f.Finding)
+    }
+
+    return status
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+    "bytes"
+    "encoding/json"
+    "flag"
+    "fmt"
+    "io"
+    "os"
+    "github.com/JakeNorman007/interpreter/ast"
+    "github.com/JakeNorman007/interpreter/lexer"
+    "github.com/JakeNorman007/interpreter/parser"
+)
+
+// runParse implements `elliott parse [-json | -dot] [-trace] [file]`. It
+// prints the parsed program, as JSON with -json or as a Graphviz graph with
+// -dot. -trace logs the parser's work to stderr. With no file it parses
+// standard input.
+func runParse(args []string) int {
+    flags := flag.NewFlagSet("parse", flag.ExitOnError)
+    asJSON := flags.Bool("json", false, "print the AST as JSON")
+    asDOT := flags.Bool("dot", false, "print the AST as a Graphviz DOT graph")
+    trace := flags.Bool("trace", false, "trace the parse functions and precedence checks to stderr")
+    flags.Usage = func() {
+        fmt.Fprintln(flags.Output(), "usage: elliott parse [-json | -dot] [-trace] [file]")
+        flags.PrintDefaults()
+    }
+    flags.Parse(args)
+
+    if *asJSON && *asDOT {
+        flags.Usage()
+        return 2
+    }
+
+    name := "<stdin>"
+    var src []byte
+    var err error
+    switch flags.NArg() {
+    case 0:
+        src, err = io.ReadAll(os.Stdin)
+    case 1:
+        name = flags.Arg(0)
+        src, err = os.ReadFile(name)
+    default:
+        flags.Usage()
+        return 2
+    }
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        return 1
+    }
+
+    var opts []parser.Option
+    if *trace {
+        opts = append(opts, parser.Trace(os.Stderr))
+    }
+
+    p := parser.New(lexer.New(string(src)), opts...)
+    program := p.ParseProgram()
+    if len(p.Errors()) != 0 {
+        for _, msg := range p.Errors() {
+            fmt.Fprintf(os.Stderr, "%s: %s\n", name, msg)
+        }
+        return 1
+    }
+
+    if *asDOT {
+        fmt.Print(ast.DOT(program))
+        return 0
+    }
+
+    if !*asJSON {
+        fmt.Println(program.String())
+        return 0
+    }
+
+    data, err := json.Marshal(program)
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        return 1
+    }
+
+    var out bytes.Buffer
+    json.Indent(&out, data, "", "  ")
+    out.WriteByte('\n')
+    os.Stdout.Write(out.Bytes())
+
+    return 0
+}
@@ -2,36 +2,47 @@ package evaluator
 
 import (
 	"fmt"
+	"sort"
+	"unicode/utf8"
 
 	"github.com/JakeNorman007/interpreter/object"
 )
 
+// maxPrealloc bounds how many elements are reserved up front when the length
+// of an iterable is known. A huge range then grows its slice as it is walked
+// instead of failing to allocate before producing anything.
+const maxPrealloc = 1 << 16
+
 var builtins = map[string]*object.Builtin {
     "len": &object.Builtin {
+        Arity: 1,
         Fn: func(args ...object.Object) object.Object {
             if len(args) != 1 {
-                return newError("wrong number of arguments, got=%d, want=1", len(args))
+                return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments, got=%d, want=1", len(args))
             }
 
             switch arg := args[0].(type) {
             case *object.String:
-                return &object.Integer{Value: int64(len(arg.Value))}
+                return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
             case *object.Array:
                 return &object.Integer{Value: int64(len(arg.Elements))}
+            case *object.Range:
+                return &object.Integer{Value: arg.Len()}
             default:
-                return newError("argument to `len` not supported, got %s", args[0].Type())
+                return newKindError(object.TYPE_ERROR, "argument to `len` not supported, got %s", args[0].Type())
             }
         },
     },
 
     "first": &object.Builtin {
+        Arity: 1,
         Fn: func(args ...object.Object) object.Object {
             if len(args) != 1 {
-                return newError("wrong number of arguments, got=%d, want=1", len(args))
+                return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments, got=%d, want=1", len(args))
             }
 
             if args[0].Type() != object.ARRAY_OBJ {
-                return newError("argument to `first` must be ARRAY, got=%s", args[0].Type())
+                return newKindError(object.TYPE_ERROR, "argument to `first` must be ARRAY, got=%s", args[0].Type())
             }
 
             arr := args[0].(*object.Array)
@@ -44,13 +55,14 @@ var builtins = map[string]*object.Builtin {
     },
 
     "last": &object.Builtin {
+        Arity: 1,
         Fn: func(args ...object.Object) object.Object {
             if len(args) != 1 {
-                return newError("wrong number of arguments, got=%d, want=1", len(args))
+                return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments, got=%d, want=1", len(args))
             }
 
             if args[0].Type() != object.ARRAY_OBJ {
-                return newError("argument to `first` must be ARRAY, got=%s", args[0].Type())
+                return newKindError(object.TYPE_ERROR, "argument to `first` must be ARRAY, got=%s", args[0].Type())
             }
 
             arr := args[0].(*object.Array)
@@ -64,13 +76,14 @@ var builtins = map[string]*object.Builtin {
     },
     
     "rest": &object.Builtin {
+        Arity: 1,
         Fn: func(args ...object.Object) object.Object {
             if len(args) != 1 {
-                return newError("wrong number of arguments, got=%d, want=1", len(args))
+                return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments, got=%d, want=1", len(args))
             }
 
             if args[0].Type() != object.ARRAY_OBJ {
-                return newError("argument to `first` must be ARRAY, got=%s", args[0].Type())
+                return newKindError(object.TYPE_ERROR, "argument to `first` must be ARRAY, got=%s", args[0].Type())
             }
 
             arr := args[0].(*object.Array)
@@ -87,13 +100,14 @@ var builtins = map[string]*object.Builtin {
     },
 
     "push": &object.Builtin {
+        Arity: 2,
         Fn: func(args ...object.Object) object.Object {
             if len(args) != 2 {
-                return newError("wrong number of arguments, got=%d, want=2", len(args))
+                return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments, got=%d, want=2", len(args))
             }
 
             if args[0].Type() != object.ARRAY_OBJ {
-                return newError("argument to `first` must be ARRAY, got=%s", args[0].Type())
+                return newKindError(object.TYPE_ERROR, "argument to `first` must be ARRAY, got=%s", args[0].Type())
             }
 
             arr := args[0].(*object.Array)
@@ -107,7 +121,32 @@ var builtins = map[string]*object.Builtin {
         },
     },
 
+    "array": &object.Builtin {
+        Arity: 1,
+        Fn: func(args ...object.Object) object.Object {
+            if len(args) != 1 {
+                return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments, got=%d, want=1", len(args))
+            }
+
+            elements := []object.Object{}
+            if r, ok := args[0].(*object.Range); ok {
+                elements = make([]object.Object, 0, min(r.Len(), maxPrealloc))
+            }
+
+            err := iterate(args[0], func(el object.Object) object.Object {
+                elements = append(elements, el)
+                return nil
+            })
+            if err != nil {
+                return err
+            }
+
+            return &object.Array{Elements: elements}
+        },
+    },
+
     "print": &object.Builtin{
+        Arity: -1,
         Fn: func(args ...object.Object) object.Object {
             for _, arg := range args {
                 fmt.Println(arg.Inspect())
@@ -117,3 +156,20 @@ var builtins = map[string]*object.Builtin {
         },
     },
 }
+
+// LookupBuiltin returns the builtin function called name, if there is one.
+func LookupBuiltin(name string) (*object.Builtin, bool) {
+    builtin, ok := builtins[name]
+    return builtin, ok
+}
+
+// BuiltinNames returns the names of the builtin functions in sorted order.
+func BuiltinNames() []string {
+    names := make([]string, 0, len(builtins))
+    for name := range builtins {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    return names
+}
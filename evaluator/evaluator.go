@@ -2,6 +2,8 @@ package evaluator
 
 import (
 	"fmt"
+	"strings"
+	"unicode/utf8"
 	"github.com/JakeNorman007/interpreter/ast"
 	"github.com/JakeNorman007/interpreter/object"
 )
@@ -26,6 +28,22 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
             return val
         }
         return &object.ReturnValue{Value: val}
+    case *ast.ThrowStatement:
+        val := Eval(node.Value, env)
+        if isError(val) {
+            return val
+        }
+        return newThrownError(val)
+    case *ast.DeferStatement:
+        if !env.Defer(node.Call) {
+            return newError("defer is only allowed inside a function")
+        }
+    case *ast.TryExpression:
+        return evalTryExpression(node, env)
+    case *ast.ImportStatement:
+        return evalImportStatement(node, env)
+    case *ast.ExportStatement:
+        return evalExportStatement(node, env)
     case *ast.IntegerLiteral:
         return &object.Integer{Value: node.Value}
     case *ast.Boolean:
@@ -41,25 +59,37 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
         if isError(val) {
             return val
         }
-        env.Set(node.Name.Value, val)
+        if node.Pattern != nil {
+            return evalDestructuringLet(node, val, env)
+        }
+        if err := declare(env, node.Name.Value, val, node.IsConst()); err != nil {
+            return err
+        }
     case *ast.FunctionLiteral:
-            params := node.Parameters
-            body := node.Body
-            return &object.Function{Parameters: params, Env: env, Body: body}
+        return newFunction(node, env)
+    case *ast.FunctionStatement:
+        fn := newFunction(node.Function, env)
+        fn.Name = node.Name.Value
+        if err := declare(env, node.Name.Value, fn, false); err != nil {
+            return err
+        }
     case *ast.StringLiteral:
         return &object.String{Value: node.Value}
     case *ast.CallExpression:
-        function := Eval(node.Function, env)
-        if isError(function) {
-            return function
-        }
-
-        args := evalExpression(node.Arguments, env)
-        if len(args) == 1 && isError(args[0]) {
-            return args[0]
+        if isQuoteCall(node) {
+            return quote(node.Arguments, env)
         }
-
-        return applyFunction(function, args)
+        return evalCallExpression(node, env, nil)
+    case *ast.MacroLiteral:
+        return newError("macros can only be defined by a top-level let statement")
+    case *ast.PipelineExpression:
+        return evalPipelineExpression(node, env)
+    case *ast.RangeExpression:
+        return evalRangeExpression(node, env)
+    case *ast.SpreadExpression:
+        return newError("spread operator is only allowed in call arguments")
+    case *ast.KeywordArgument:
+        return newError("keyword argument is only allowed in call arguments")
     case *ast.Identifier:
         return evalIdentifier(node, env)
     case *ast.InfixExpression:
@@ -82,67 +112,244 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
         }
 
         return &object.Array{Elements: elements}
-    case *ast.IndexExpression:
+    case *ast.IndexExpression, *ast.PropertyExpression, *ast.SliceExpression,
+        *ast.OptionalIndexExpression, *ast.OptionalPropertyExpression:
+        result, _ := evalChain(node.(ast.Expression), env)
+        return result
+    case *ast.CoalesceExpression:
         left := Eval(node.Left, env)
-        if isError(left) {
+        if isError(left) || left != NULL {
             return left
         }
-
-        index := Eval(node.Index, env)
-        if isError(index) {
-            return index
-        }
-        return evalIndexExpression(left, index)
+        return Eval(node.Right, env)
     case *ast.HashLiteral:
         return evalHashLiteral(node, env)
+    case *ast.ListComprehension:
+        return evalListComprehension(node, env)
+    case *ast.HashComprehension:
+        return evalHashComprehension(node, env)
+    case *ast.MatchExpression:
+        return evalMatchExpression(node, env)
     }
 
     return nil
 }
 
 func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
-    pairs := make(map[object.HashKey]object.HashPair)
+    hash := object.NewHash()
 
-    for keyNode, valueNode := range node.Pairs {
-        key := Eval(keyNode, env)
+    for _, pairNode := range node.Pairs {
+        key := Eval(pairNode.Key, env)
         if isError(key) {
             return key
         }
 
         hashKey, ok := key.(object.Hashable)
         if !ok {
-            return newError("unusable hash key: %s", key.Type())
+            return newKindError(object.TYPE_ERROR, "unusable hash key: %s", key.Type())
         }
 
-        value := Eval(valueNode, env)
+        value := Eval(pairNode.Value, env)
         if isError(value) {
             return value
         }
 
-        hashed := hashKey.HashKey()
-        pairs[hashed] = object.HashPair{Key: key, Value: value}
+        hash.Set(hashKey.HashKey(), object.HashPair{Key: key, Value: value})
     }
 
-    return &object.Hash{Pairs: pairs}
+    return hash
 }
 
 func evalIndexExpression(left, index object.Object) object.Object {
     switch {
     case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
         return evalArrayIndexExpression(left, index)
+    case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+        return evalStringIndexExpression(left, index)
+    case left.Type() == object.RANGE_OBJ && index.Type() == object.INTEGER_OBJ:
+        return evalRangeIndexExpression(left, index)
     case left.Type() == object.HASH_OBJ:
         return evalHashIndexExpression(left, index)
     default:
-        return newError("index operator not supported: %s", left.Type())
+        return newKindError(object.TYPE_ERROR, "index operator not supported: %s", left.Type())
+    }
+}
+
+// evalCallExpression evaluates a call, passing piped ahead of the call's own
+// arguments.
+func evalCallExpression(node *ast.CallExpression, env *object.Environment, piped []object.Object) object.Object {
+    result, _ := evalCall(node, env, piped)
+    return result
+}
+
+// evalCall evaluates a call as a link of a chain: when its callee
+// short-circuits the call is skipped, and evalCall reports that the same way
+// evalChain does.
+func evalCall(node *ast.CallExpression, env *object.Environment, piped []object.Object) (object.Object, bool) {
+    var receiver object.Object
+    var function object.Object
+
+    switch callee := node.Function.(type) {
+    case *ast.PropertyExpression:
+        obj, shortCircuited := evalChain(callee.Object, env)
+        if isError(obj) || shortCircuited {
+            return obj, shortCircuited
+        }
+        receiver = obj
+        function = evalPropertyExpression(receiver, callee.Property.Value)
+    case *ast.OptionalPropertyExpression:
+        obj, shortCircuited := evalChain(callee.Object, env)
+        if isError(obj) {
+            return obj, false
+        }
+        if shortCircuited || obj == NULL {
+            return NULL, true
+        }
+        receiver = obj
+        function = evalPropertyExpression(receiver, callee.Property.Value)
+    default:
+        var shortCircuited bool
+        function, shortCircuited = evalChain(node.Function, env)
+        if shortCircuited {
+            return function, true
+        }
+    }
+
+    if isError(function) {
+        return function, false
+    }
+
+    //module functions are plain functions, not methods of the module
+    if _, ok := receiver.(*object.Module); ok {
+        receiver = nil
+    }
+
+    args := evalArguments(node.Arguments, env)
+    if len(args) == 1 && isError(args[0]) {
+        return args[0], false
+    }
+    args = append(piped, args...)
+
+    kwargs, err := evalKeywordArguments(node.Arguments, env)
+    if err != nil {
+        return err, false
+    }
+
+    return applyCall(function, receiver, args, kwargs), false
+}
+
+func evalPipelineExpression(node *ast.PipelineExpression, env *object.Environment) object.Object {
+    left := Eval(node.Left, env)
+    if isError(left) {
+        return left
+    }
+
+    if call, ok := node.Right.(*ast.CallExpression); ok {
+        return wrapPipelineError(node, evalCallExpression(call, env, []object.Object{left}))
+    }
+
+    function := Eval(node.Right, env)
+    if isError(function) {
+        return function
+    }
+
+    switch function.(type) {
+    case *object.Function, *object.Builtin:
+        return wrapPipelineError(node, applyFunction(function, []object.Object{left}))
+    default:
+        return newKindError(object.TYPE_ERROR, "cannot pipe into %s: %s", function.Type(), node.String())
     }
 }
 
+// wrapPipelineError prefixes an error raised by the call a pipeline was
+// rewritten into with the pipeline itself, since the call as written lacks
+// the piped argument. Thrown errors are left as they are for catch blocks.
+func wrapPipelineError(node *ast.PipelineExpression, result object.Object) object.Object {
+    err, ok := result.(*object.Error)
+    if !ok || err.Kind == object.THROWN_ERROR {
+        return result
+    }
+
+    return &object.Error{Kind: err.Kind, Message: node.String() + ": " + err.Message, Payload: err.Payload, Stack: err.Stack}
+}
+
+// evalChain evaluates a chain of property, index and slice accesses and
+// calls. Once an optional access (?., ?.[ or ?.[:]) finds NULL the rest of
+// the chain is skipped, which evalChain reports by returning NULL and true.
+func evalChain(node ast.Expression, env *object.Environment) (object.Object, bool) {
+    switch node := node.(type) {
+    case *ast.PropertyExpression:
+        obj, shortCircuited := evalChain(node.Object, env)
+        if isError(obj) || shortCircuited {
+            return obj, shortCircuited
+        }
+        return evalPropertyExpression(obj, node.Property.Value), false
+    case *ast.OptionalPropertyExpression:
+        obj, shortCircuited := evalChain(node.Object, env)
+        if isError(obj) || shortCircuited {
+            return obj, shortCircuited
+        }
+        if obj == NULL {
+            return NULL, true
+        }
+        return evalPropertyExpression(obj, node.Property.Value), false
+    case *ast.IndexExpression:
+        left, shortCircuited := evalChain(node.Left, env)
+        if isError(left) || shortCircuited {
+            return left, shortCircuited
+        }
+        index := Eval(node.Index, env)
+        if isError(index) {
+            return index, false
+        }
+        return evalIndexExpression(left, index), false
+    case *ast.OptionalIndexExpression:
+        left, shortCircuited := evalChain(node.Left, env)
+        if isError(left) || shortCircuited {
+            return left, shortCircuited
+        }
+        if left == NULL {
+            return NULL, true
+        }
+        index := Eval(node.Index, env)
+        if isError(index) {
+            return index, false
+        }
+        return evalIndexExpression(left, index), false
+    case *ast.SliceExpression:
+        left, shortCircuited := evalChain(node.Left, env)
+        if isError(left) || shortCircuited {
+            return left, shortCircuited
+        }
+        if node.Optional && left == NULL {
+            return NULL, true
+        }
+        return evalSliceExpression(node, left, env), false
+    case *ast.CallExpression:
+        return evalCall(node, env, nil)
+    default:
+        return Eval(node, env), false
+    }
+}
+
+func evalPropertyExpression(obj object.Object, name string) object.Object {
+    if mod, ok := obj.(*object.Module); ok {
+        return evalModuleMember(mod, name)
+    }
+
+    if obj.Type() != object.HASH_OBJ {
+        return newKindError(object.TYPE_ERROR, "property access not supported: %s", obj.Type())
+    }
+
+    return evalHashIndexExpression(obj, &object.String{Value: name})
+}
+
 func evalHashIndexExpression(hash, index object.Object) object.Object {
     hashObject := hash.(*object.Hash)
 
     key, ok := index.(object.Hashable)
     if !ok {
-        return newError("unusable as hash key: %s", index.Type())
+        return newKindError(object.TYPE_ERROR, "unusable as hash key: %s", index.Type())
     }
 
     pair, ok := hashObject.Pairs[key.HashKey()]
@@ -158,6 +365,10 @@ func evalArrayIndexExpression(array, index object.Object) object.Object {
     idx := index.(*object.Integer).Value
     max := int64(len(arrayObject.Elements) - 1)
 
+    if idx < 0 {
+        idx += max + 1
+    }
+
     if idx < 0 || idx > max {
         return NULL
     }
@@ -165,27 +376,542 @@ func evalArrayIndexExpression(array, index object.Object) object.Object {
     return arrayObject.Elements[idx]
 }
 
+// evalStringIndexExpression indexes a string by character, not byte, so
+// non-ASCII text indexes the way it reads.
+func evalStringIndexExpression(str, index object.Object) object.Object {
+    value := []rune(str.(*object.String).Value)
+    idx := index.(*object.Integer).Value
+    max := int64(len(value) - 1)
+
+    if idx < 0 {
+        idx += max + 1
+    }
+
+    if idx < 0 || idx > max {
+        return NULL
+    }
+
+    return &object.String{Value: string(value[idx])}
+}
+
+func evalRangeIndexExpression(rng, index object.Object) object.Object {
+    rangeObject := rng.(*object.Range)
+    idx := index.(*object.Integer).Value
+
+    if rangeObject.Len() == 0 {
+        return NULL
+    }
+
+    //Len saturates for ranges too long to count in an int64, so bounds are
+    //checked against the unsigned distance from Start to the last element
+    span := uint64(rangeObject.Last() - rangeObject.Start)
+
+    if idx < 0 {
+        if uint64(-(idx + 1)) > span {
+            return NULL
+        }
+        return &object.Integer{Value: rangeObject.Last() + (idx + 1)}
+    }
+
+    if uint64(idx) > span {
+        return NULL
+    }
+
+    return &object.Integer{Value: rangeObject.At(idx)}
+}
+
+func evalRangeExpression(node *ast.RangeExpression, env *object.Environment) object.Object {
+    start := Eval(node.Start, env)
+    if isError(start) {
+        return start
+    }
+
+    end := Eval(node.End, env)
+    if isError(end) {
+        return end
+    }
+
+    if start.Type() != object.INTEGER_OBJ || end.Type() != object.INTEGER_OBJ {
+        return newKindError(object.TYPE_ERROR, "range bounds must be INTEGER, got %s%s%s", start.Type(), node.Token.Literal, end.Type())
+    }
+
+    return &object.Range{
+        Start:      start.(*object.Integer).Value,
+        End:        end.(*object.Integer).Value,
+        Inclusive:  node.Inclusive,
+    }
+}
+
+// iterate calls fn with each element of an array, range or string (one
+// character at a time), or each key of a hash in insertion order, stopping
+// at the first error fn returns.
+func iterate(obj object.Object, fn func(object.Object) object.Object) object.Object {
+    switch obj := obj.(type) {
+    case *object.Array:
+        for _, el := range obj.Elements {
+            if err := fn(el); isError(err) {
+                return err
+            }
+        }
+    case *object.Range:
+        for i := int64(0); i < obj.Len(); i++ {
+            if err := fn(&object.Integer{Value: obj.At(i)}); isError(err) {
+                return err
+            }
+        }
+    case *object.String:
+        for _, r := range obj.Value {
+            if err := fn(&object.String{Value: string(r)}); isError(err) {
+                return err
+            }
+        }
+    case *object.Hash:
+        for _, pair := range obj.Ordered() {
+            if err := fn(pair.Key); isError(err) {
+                return err
+            }
+        }
+    default:
+        return newKindError(object.TYPE_ERROR, "%s is not iterable", obj.Type())
+    }
+
+    return nil
+}
+
+// iteratePairs is like iterate, but hands fn key/value pairs for hashes, in
+// insertion order, and index/element pairs for everything else.
+func iteratePairs(obj object.Object, fn func(key, value object.Object) object.Object) object.Object {
+    if hash, ok := obj.(*object.Hash); ok {
+        for _, pair := range hash.Ordered() {
+            if err := fn(pair.Key, pair.Value); isError(err) {
+                return err
+            }
+        }
+        return nil
+    }
+
+    var index int64
+    return iterate(obj, func(el object.Object) object.Object {
+        key := &object.Integer{Value: index}
+        index++
+        return fn(key, el)
+    })
+}
+
+// iterableLen returns a capacity hint for the items iterating obj yields.
+// Ranges are lazy, so their hint is capped at maxPrealloc.
+func iterableLen(obj object.Object) int {
+    switch obj := obj.(type) {
+    case *object.Array:
+        return len(obj.Elements)
+    case *object.Range:
+        return int(min(obj.Len(), maxPrealloc))
+    case *object.String:
+        return len(obj.Value)
+    case *object.Hash:
+        return len(obj.Pairs)
+    }
+
+    return 0
+}
+
+// evalComprehension evaluates the for/if clause shared by list and hash
+// comprehensions, calling yield with a fresh scope for every item that
+// passes the condition.
+func evalComprehension(variables []*ast.Identifier, iterable object.Object, condition ast.Expression,
+    env *object.Environment, yield func(*object.Environment) object.Object) object.Object {
+    body := func(scope *object.Environment) object.Object {
+        if condition != nil {
+            ok := Eval(condition, scope)
+            if isError(ok) {
+                return ok
+            }
+            if !isTruthy(ok) {
+                return nil
+            }
+        }
+        return yield(scope)
+    }
+
+    if len(variables) == 1 {
+        return iterate(iterable, func(el object.Object) object.Object {
+            scope := object.NewEnclosedEnvironment(env)
+            scope.Set(variables[0].Value, el)
+            return body(scope)
+        })
+    }
+
+    return iteratePairs(iterable, func(key, value object.Object) object.Object {
+        scope := object.NewEnclosedEnvironment(env)
+        scope.Set(variables[0].Value, key)
+        scope.Set(variables[1].Value, value)
+        return body(scope)
+    })
+}
+
+func evalListComprehension(node *ast.ListComprehension, env *object.Environment) object.Object {
+    iterable := Eval(node.Iterable, env)
+    if isError(iterable) {
+        return iterable
+    }
+
+    elements := make([]object.Object, 0, iterableLen(iterable))
+
+    err := evalComprehension(node.Variables, iterable, node.Condition, env, func(scope *object.Environment) object.Object {
+        el := Eval(node.Element, scope)
+        if isError(el) {
+            return el
+        }
+        elements = append(elements, el)
+        return nil
+    })
+    if isError(err) {
+        return err
+    }
+
+    return &object.Array{Elements: elements}
+}
+
+func evalHashComprehension(node *ast.HashComprehension, env *object.Environment) object.Object {
+    iterable := Eval(node.Iterable, env)
+    if isError(iterable) {
+        return iterable
+    }
+
+    hash := object.NewHash()
+
+    err := evalComprehension(node.Variables, iterable, node.Condition, env, func(scope *object.Environment) object.Object {
+        key := Eval(node.Key, scope)
+        if isError(key) {
+            return key
+        }
+
+        hashKey, ok := key.(object.Hashable)
+        if !ok {
+            return newKindError(object.TYPE_ERROR, "unusable hash key: %s", key.Type())
+        }
+
+        value := Eval(node.Value, scope)
+        if isError(value) {
+            return value
+        }
+
+        hash.Set(hashKey.HashKey(), object.HashPair{Key: key, Value: value})
+        return nil
+    })
+    if isError(err) {
+        return err
+    }
+
+    return hash
+}
+
+func evalInExpression(left, right object.Object) object.Object {
+    switch right := right.(type) {
+    case *object.Range:
+        integer, ok := left.(*object.Integer)
+        return nativeBoolToBooleanObject(ok && right.Contains(integer.Value))
+    case *object.Array:
+        for _, el := range right.Elements {
+            if objectsEqual(left, el) {
+                return TRUE
+            }
+        }
+        return FALSE
+    case *object.String:
+        str, ok := left.(*object.String)
+        if !ok {
+            return newKindError(object.TYPE_ERROR, "type mismatch: %s in STRING", left.Type())
+        }
+        return nativeBoolToBooleanObject(strings.Contains(right.Value, str.Value))
+    case *object.Hash:
+        key, ok := left.(object.Hashable)
+        if !ok {
+            return newKindError(object.TYPE_ERROR, "unusable as hash key: %s", left.Type())
+        }
+        _, ok = right.Pairs[key.HashKey()]
+        return nativeBoolToBooleanObject(ok)
+    default:
+        return newKindError(object.TYPE_ERROR, "unknown operator: %s in %s", left.Type(), right.Type())
+    }
+}
+
+// evalSliceExpression slices left, the already evaluated node.Left, with
+// node's bounds.
+func evalSliceExpression(node *ast.SliceExpression, left object.Object, env *object.Environment) object.Object {
+    var length int64
+    switch left := left.(type) {
+    case *object.Array:
+        length = int64(len(left.Elements))
+    case *object.String:
+        length = int64(utf8.RuneCountInString(left.Value))
+    default:
+        return newKindError(object.TYPE_ERROR, "slice operator not supported: %s", left.Type())
+    }
+
+    bounds := []ast.Expression{node.Start, node.End, node.Step}
+    values := make([]*int64, len(bounds))
+    for i, exp := range bounds {
+        if exp == nil {
+            continue
+        }
+
+        evaluated := Eval(exp, env)
+        if isError(evaluated) {
+            return evaluated
+        }
+
+        integer, ok := evaluated.(*object.Integer)
+        if !ok {
+            return newKindError(object.TYPE_ERROR, "slice indices must be INTEGER, got %s", evaluated.Type())
+        }
+        values[i] = &integer.Value
+    }
+
+    step := int64(1)
+    if values[2] != nil {
+        step = *values[2]
+    }
+    if step == 0 {
+        return newError("slice step cannot be zero")
+    }
+
+    start, end := sliceBounds(length, values[0], values[1], step)
+    count := sliceCount(start, end, step)
+
+    switch left := left.(type) {
+    case *object.Array:
+        elements := make([]object.Object, 0, count)
+        for k := int64(0); k < count; k++ {
+            elements = append(elements, left.Elements[start + k * step])
+        }
+        return &object.Array{Elements: elements}
+    default:
+        value := []rune(left.(*object.String).Value)
+        out := make([]rune, 0, count)
+        for k := int64(0); k < count; k++ {
+            out = append(out, value[start + k * step])
+        }
+        return &object.String{Value: string(out)}
+    }
+}
+
+// sliceCount returns how many elements a slice from start towards end
+// selects. It is worked out up front so stepping never adds step to an index
+// past end, which overflows for steps near the int64 limits.
+func sliceCount(start, end, step int64) int64 {
+    switch {
+    case step > 0 && start < end:
+        return (end - start - 1) / step + 1
+    case step < 0 && start > end:
+        //-step stays negative for math.MinInt64, but the quotient is then 0,
+        //which is still right since start - end never exceeds the length
+        return (start - end - 1) / -step + 1
+    }
+
+    return 0
+}
+
+// sliceBounds clamps start and end the same way Python does, so negative
+// indices count from the end and out of range bounds never panic.
+func sliceBounds(length int64, start, end *int64, step int64) (int64, int64) {
+    clamp := func(v int64) int64 {
+        if v < 0 {
+            v += length
+            if v < 0 {
+                if step < 0 {
+                    return -1
+                }
+                return 0
+            }
+        } else if v >= length {
+            if step < 0 {
+                return length - 1
+            }
+            return length
+        }
+        return v
+    }
+
+    var s, e int64
+    if step > 0 {
+        s, e = 0, length
+    } else {
+        s, e = length - 1, -1
+    }
+
+    if start != nil {
+        s = clamp(*start)
+    }
+    if end != nil {
+        e = clamp(*end)
+    }
+
+    return s, e
+}
+
+func newFunction(fl *ast.FunctionLiteral, env *object.Environment) *object.Function {
+    return &object.Function{
+        Name:       fl.Name,
+        Parameters: fl.Parameters,
+        Defaults:   fl.Defaults,
+        Rest:       fl.Rest,
+        Body:       fl.Body,
+        Env:        env,
+    }
+}
+
+type keywordArgument struct {
+    name    string
+    value   object.Object
+}
+
 func applyFunction(fn object.Object, args []object.Object) object.Object {
+    return applyCall(fn, nil, args, nil)
+}
+
+// applyCall calls fn with args and kwargs. A non-nil receiver is the hash a
+// method was looked up on and is bound to self inside the function body.
+func applyCall(fn object.Object, receiver object.Object, args []object.Object, kwargs []keywordArgument) object.Object {
     switch fn := fn.(type) {
     case *object.Function:
-        extendedEnv := extendFunctionEnv(fn, args)
+        extendedEnv, err := extendFunctionEnv(fn, receiver, args, kwargs)
+        if err != nil {
+            return addStackFrame(err, fn)
+        }
         evaluated := Eval(fn.Body, extendedEnv)
-        return unwrapReturnValue(evaluated)
+        evaluated = runDeferred(extendedEnv, evaluated)
+        return addStackFrame(unwrapReturnValue(evaluated), fn)
     case *object.Builtin:
+        if len(kwargs) > 0 {
+            return newKindError(object.ARGUMENT_ERROR, "builtin function does not accept keyword arguments")
+        }
         return fn.Fn(args...)
     default:
-        return newError("not a function: %s", fn.Type())
+        return newKindError(object.TYPE_ERROR, "not a function: %s", fn.Type())
     }
 }
 
-func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
-    env := object.NewEnclosedEnvironment(fn.Env)
+// addStackFrame records that an error left the named function fn, so the
+// error reports the calls it unwound through. Other results, and errors
+// leaving anonymous functions, are returned unchanged.
+func addStackFrame(result object.Object, fn *object.Function) object.Object {
+    err, ok := result.(*object.Error)
+    if !ok || fn.Name == "" {
+        return result
+    }
+
+    framed := *err
+    framed.Stack = append(append([]string{}, err.Stack...), fn.Name)
+
+    return &framed
+}
+
+func extendFunctionEnv(fn *object.Function, receiver object.Object, args []object.Object, kwargs []keywordArgument) (*object.Environment, *object.Error) {
+    keywords := make(map[string]object.Object)
+    for _, kw := range kwargs {
+        paramIdx := parameterIndex(fn, kw.name)
+        if paramIdx < 0 {
+            return nil, newKindError(object.ARGUMENT_ERROR, "unexpected keyword argument %s", kw.name)
+        }
+        if paramIdx < len(args) {
+            return nil, newKindError(object.ARGUMENT_ERROR, "multiple values for argument %s", kw.name)
+        }
+        keywords[kw.name] = kw.value
+    }
+
+    if err := checkArity(fn, len(args) + len(kwargs)); err != nil {
+        return nil, err
+    }
+
+    env := object.NewFunctionEnvironment(fn.Env)
+
+    if receiver != nil {
+        env.Set("self", receiver)
+    }
+
+    if env.Strict() {
+        for _, param := range fn.Parameters {
+            if _, ok := builtins[param.Value]; ok {
+                return nil, newKindError(object.NAME_ERROR, "cannot shadow builtin %s", param.Value)
+            }
+        }
+    }
 
     for paramIdx, param := range fn.Parameters {
-        env.Set(param.Value, args[paramIdx])
+        if paramIdx < len(args) {
+            env.Set(param.Value, args[paramIdx])
+            continue
+        }
+
+        if val, ok := keywords[param.Value]; ok {
+            env.Set(param.Value, val)
+            continue
+        }
+
+        if paramIdx >= len(fn.Defaults) || fn.Defaults[paramIdx] == nil {
+            return nil, newKindError(object.ARGUMENT_ERROR, "missing argument for parameter %s", param.Value)
+        }
+
+        // defaults are evaluated in the call's env so they can see earlier parameters
+        val := Eval(fn.Defaults[paramIdx], env)
+        if err, ok := val.(*object.Error); ok {
+            return nil, err
+        }
+        env.Set(param.Value, val)
     }
 
-    return env
+    if fn.Rest != nil {
+        rest := []object.Object{}
+        if len(args) > len(fn.Parameters) {
+            rest = append(rest, args[len(fn.Parameters):]...)
+        }
+        env.Set(fn.Rest.Value, &object.Array{Elements: rest})
+    }
+
+    return env, nil
+}
+
+// runDeferred runs the deferred expressions of a finished call. The first
+// error wins: a deferred error only replaces result when result isn't one.
+func runDeferred(env *object.Environment, result object.Object) object.Object {
+    for _, d := range env.TakeDeferred() {
+        evaluated := Eval(d.Expression, d.Env)
+        if isError(evaluated) && !isError(result) {
+            result = evaluated
+        }
+    }
+
+    return result
+}
+
+func parameterIndex(fn *object.Function, name string) int {
+    for i, param := range fn.Parameters {
+        if param.Value == name {
+            return i
+        }
+    }
+
+    return -1
+}
+
+func checkArity(fn *object.Function, got int) *object.Error {
+    max := len(fn.Parameters)
+    min := max
+    for min > 0 && min <= len(fn.Defaults) && fn.Defaults[min - 1] != nil {
+        min--
+    }
+
+    switch {
+    case fn.Rest != nil && got < min:
+        return newKindError(object.ARGUMENT_ERROR, "expected at least %d arguments, got %d", min, got)
+    case fn.Rest == nil && min == max && got != max:
+        return newKindError(object.ARGUMENT_ERROR, "expected %d arguments, got %d", max, got)
+    case fn.Rest == nil && (got < min || got > max):
+        return newKindError(object.ARGUMENT_ERROR, "expected %d to %d arguments, got %d", min, max, got)
+    }
+
+    return nil
 }
 
 func unwrapReturnValue(obj object.Object) object.Object {
@@ -211,6 +937,61 @@ func evalExpression(exps []ast.Expression, env *object.Environment) []object.Obj
     return result
 }
 
+func evalArguments(exps []ast.Expression, env *object.Environment) []object.Object {
+    result := []object.Object{}
+
+    for _, e := range exps {
+        if _, ok := e.(*ast.KeywordArgument); ok {
+            continue
+        }
+
+        spread, ok := e.(*ast.SpreadExpression)
+        if !ok {
+            evaluated := Eval(e, env)
+            if isError(evaluated) {
+                return []object.Object{evaluated}
+            }
+
+            result = append(result, evaluated)
+            continue
+        }
+
+        evaluated := Eval(spread.Value, env)
+        if isError(evaluated) {
+            return []object.Object{evaluated}
+        }
+
+        arr, ok := evaluated.(*object.Array)
+        if !ok {
+            return []object.Object{newKindError(object.TYPE_ERROR, "cannot spread %s, expected ARRAY", evaluated.Type())}
+        }
+
+        result = append(result, arr.Elements...)
+    }
+
+    return result
+}
+
+func evalKeywordArguments(exps []ast.Expression, env *object.Environment) ([]keywordArgument, object.Object) {
+    var kwargs []keywordArgument
+
+    for _, e := range exps {
+        kw, ok := e.(*ast.KeywordArgument)
+        if !ok {
+            continue
+        }
+
+        evaluated := Eval(kw.Value, env)
+        if isError(evaluated) {
+            return nil, evaluated
+        }
+
+        kwargs = append(kwargs, keywordArgument{name: kw.Name.Value, value: evaluated})
+    }
+
+    return kwargs, nil
+}
+
 func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
     if val, ok := env.Get(node.Value); ok {
         return val
@@ -220,7 +1001,7 @@ func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object
         return builtin
     }
 
-    return newError("identifier not found: " + node.Value)
+    return newKindError(object.NAME_ERROR, "identifier not found: " + node.Value)
 
 }
 
@@ -241,6 +1022,90 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
     return result
 }
 
+func evalTryExpression(te *ast.TryExpression, env *object.Environment) object.Object {
+    result := Eval(te.Block, env)
+
+    if err, ok := result.(*object.Error); ok && te.Catch != nil {
+        catchEnv := object.NewEnclosedEnvironment(env)
+        if te.Param != nil {
+            catchEnv.Set(te.Param.Value, errorToHash(err))
+        }
+        result = Eval(te.Catch, catchEnv)
+    }
+
+    if te.Finally != nil {
+        // an error or return inside finally replaces whatever the try produced
+        finally := Eval(te.Finally, env)
+        if finally != nil {
+            ft := finally.Type()
+            if ft == object.ERROR_OBJ || ft == object.RETURN_VALUE_OBJ {
+                return finally
+            }
+        }
+    }
+
+    if result == nil {
+        return NULL
+    }
+
+    return result
+}
+
+func newThrownError(val object.Object) *object.Error {
+    err := &object.Error{Kind: object.THROWN_ERROR, Message: val.Inspect(), Payload: val}
+
+    if hash, ok := val.(*object.Hash); ok {
+        if kind, ok := hashStringValue(hash, "kind"); ok {
+            err.Kind = kind
+        }
+        if message, ok := hashStringValue(hash, "message"); ok {
+            err.Message = message
+        }
+    }
+
+    return err
+}
+
+func hashStringValue(hash *object.Hash, key string) (string, bool) {
+    pair, ok := hash.Pairs[(&object.String{Value: key}).HashKey()]
+    if !ok {
+        return "", false
+    }
+
+    str, ok := pair.Value.(*object.String)
+    if !ok {
+        return "", false
+    }
+
+    return str.Value, true
+}
+
+// errorToHash converts an error into the value bound by catch, so scripts
+// can read e.message, e.kind and e.payload.
+func errorToHash(err *object.Error) *object.Hash {
+    hash := object.NewHash()
+
+    set := func(key string, value object.Object) {
+        k := &object.String{Value: key}
+        hash.Set(k.HashKey(), object.HashPair{Key: k, Value: value})
+    }
+
+    kind := err.Kind
+    if kind == "" {
+        kind = object.RUNTIME_ERROR
+    }
+
+    set("message", &object.String{Value: err.Message})
+    set("kind", &object.String{Value: kind})
+    if err.Payload != nil {
+        set("payload", err.Payload)
+    } else {
+        set("payload", NULL)
+    }
+
+    return hash
+}
+
 func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
     condition := Eval(ie.Condition, env)
 
@@ -273,6 +1138,10 @@ func isTruthy(obj object.Object) bool {
 func evalProgram(program *ast.Program, env *object.Environment) object.Object {
     var result object.Object
 
+    if hasStrictDirective(program) {
+        env.SetStrict(true)
+    }
+
     for _, statement := range program.Statements {
         result = Eval(statement, env)
 
@@ -287,6 +1156,45 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
     return result
 }
 
+func hasStrictDirective(program *ast.Program) bool {
+    if len(program.Statements) == 0 {
+        return false
+    }
+
+    stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+    if !ok {
+        return false
+    }
+
+    str, ok := stmt.Expression.(*ast.StringLiteral)
+    return ok && str.Value == "use strict"
+}
+
+// declare binds name in env, refusing to rebind a const and, in strict mode,
+// refusing to redeclare a name in the same scope or shadow a builtin.
+func declare(env *object.Environment, name string, val object.Object, constant bool) *object.Error {
+    if env.IsConst(name) {
+        return newKindError(object.NAME_ERROR, "cannot reassign constant %s", name)
+    }
+
+    if env.Strict() {
+        if env.Has(name) {
+            return newKindError(object.NAME_ERROR, "%s is already declared in this scope", name)
+        }
+        if _, ok := builtins[name]; ok {
+            return newKindError(object.NAME_ERROR, "cannot shadow builtin %s", name)
+        }
+    }
+
+    if constant {
+        env.SetConst(name, val)
+    } else {
+        env.Set(name, val)
+    }
+
+    return nil
+}
+
 func nativeBoolToBooleanObject(input bool) *object.Boolean {
     if input {
         return TRUE
@@ -302,7 +1210,7 @@ func evalPrefixExpression(operator string, right object.Object) object.Object {
     case "-":
         return evalMinusPrefixOperatorExpression(right)
     default:
-        return newError("unknown operator: %s%s", operator, right.Type())
+        return newKindError(object.TYPE_ERROR, "unknown operator: %s%s", operator, right.Type())
     }
 }
 
@@ -321,7 +1229,7 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
     if right.Type() != object.INTEGER_OBJ {
-        return newError("unknown operator: -%s", right.Type())
+        return newKindError(object.TYPE_ERROR, "unknown operator: -%s", right.Type())
     }
 
     value := right.(*object.Integer).Value
@@ -330,6 +1238,8 @@ func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
 
 func evalInfixExpression(operator string, left, right object.Object) object.Object {
     switch {
+    case operator == "in":
+        return evalInExpression(left, right)
     case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
         return evalIntegerInfixExpression(operator, left, right)
     case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
@@ -339,15 +1249,15 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
     case operator == "!=":
         return nativeBoolToBooleanObject(left != right)
     case left.Type() != right.Type():
-        return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
+        return newKindError(object.TYPE_ERROR, "type mismatch: %s %s %s", left.Type(), operator, right.Type())
     default:
-        return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+        return newKindError(object.TYPE_ERROR, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
     }
 }
 
 func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
     if operator != "+" {
-        return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+        return newKindError(object.TYPE_ERROR, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
     }
 
     leftVal := left.(*object.String).Value
@@ -377,12 +1287,16 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
     case "!=":
         return nativeBoolToBooleanObject(leftVal != rightVal)
     default:
-        return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type()) 
+        return newKindError(object.TYPE_ERROR, "unknown operator: %s %s %s", left.Type(), operator, right.Type()) 
     }
 }
 
 func newError(format string, a ...interface{}) *object.Error {
-    return &object.Error{Message: fmt.Sprintf(format, a...)}
+    return newKindError(object.RUNTIME_ERROR, format, a...)
+}
+
+func newKindError(kind string, format string, a ...interface{}) *object.Error {
+    return &object.Error{Kind: kind, Message: fmt.Sprintf(format, a...)}
 }
 
 func isError(obj object.Object) bool {
@@ -1,7 +1,11 @@
 package evaluator
 
 import (
+    "os"
+    "path/filepath"
+    "strings"
     "testing"
+    "github.com/JakeNorman007/interpreter/ast"
     "github.com/JakeNorman007/interpreter/lexer"
     "github.com/JakeNorman007/interpreter/object"
     "github.com/JakeNorman007/interpreter/parser"
@@ -308,6 +312,7 @@ func TestBuiltinFunction(t *testing.T) {
         {`len("")`, 0},
         {`len("four")`, 4},
         {`len("Hello World")`, 11},
+        {`len("héllo")`, 5},
         {`len(1)`, "argument to `len` not supported, got INTEGER"},
         {`len("one", "two")`, "wrong number of arguments, got=2, want=1"},
     }
@@ -364,7 +369,9 @@ func TestArrayIndexExpression(t *testing.T) {
         {"let myArray = [1, 2, 3]; myArray[0] + myArray[1] + myArray[2];", 6},
         {"let myArray = [1, 2, 3]; let i = myArray[0]; myArray[i]", 2},
         {"[1, 2, 3][3]", nil},
-        {"[1, 2, 3][-1]", nil},
+        {"[1, 2, 3][-1]", 3},
+        {"[1, 2, 3][-3]", 1},
+        {"[1, 2, 3][-4]", nil},
     }
 
     for _, tt := range tests {
@@ -504,3 +511,976 @@ func testBooleanObject(t *testing.T, obj object.Object, expected bool) bool {
 
     return true
 }
+
+func TestFunctionStatements(t *testing.T) {
+    tests := []struct {
+        input       string
+        expected    int64
+    }{
+        {"func add(x, y) { x + y; } add(2, 3);", 5},
+        {"func fact(n) { if (n < 2) { return 1; } n * fact(n - 1); } fact(5);", 120},
+        {
+            `
+            func isEven(n) { if (n == 0) { return true; } isOdd(n - 1); }
+            func isOdd(n) { if (n == 0) { return false; } isEven(n - 1); }
+            if (isEven(10)) { 1 } else { 0 }
+            `, 1,
+        },
+        {"func outer() { func inner(x) { x * 2; } inner(4); } outer();", 8},
+    }
+
+    for _, tt := range tests {
+        testIntegerObject(t, testEval(tt.input), tt.expected)
+    }
+}
+
+func TestFunctionObjectName(t *testing.T) {
+    tests := []struct {
+        input       string
+        expected    string
+    }{
+        {"func add(x, y) { x + y; } add;", "add"},
+        {"let sub = func(x, y) { x - y; }; sub;", "sub"},
+        {"func(x) { x; };", ""},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(tt.input)
+        fn, ok := evaluated.(*object.Function)
+        if !ok {
+            t.Fatalf("object is not a Function, got=%T (%+v)", evaluated, evaluated)
+        }
+
+        if fn.Name != tt.expected {
+            t.Errorf("fn.Name is not %q, got=%q", tt.expected, fn.Name)
+        }
+    }
+
+    fn := testEval("func add(x, y) { x + y; } add;").(*object.Function)
+    if fn.Inspect() != "func add(x, y) {\n(x + y)\n}" {
+        t.Errorf("fn.Inspect() is wrong, got=%q", fn.Inspect())
+    }
+}
+
+func TestErrorStack(t *testing.T) {
+    tests := []struct {
+        input       string
+        expected    string
+    }{
+        {"func inner() { 5 + true } func outer() { inner() } outer();",
+            "ERROR:type mismatch: INTEGER + BOOLEAN\n\tin inner\n\tin outer"},
+        {"func f(x) { x } f();", "ERROR:expected 1 arguments, got 0\n\tin f"},
+        {"func f() { throw \"boom\" } let g = func() { f() }; g();", "ERROR:boom\n\tin f\n\tin g"},
+        {"func f() { foobar } func(x) { f() }(1);", "ERROR:identifier not found: foobar\n\tin f"},
+        {"func f() { foobar } try { f() } catch (e) { e.message }", "identifier not found: foobar"},
+        {"5 + true", "ERROR:type mismatch: INTEGER + BOOLEAN"},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(tt.input)
+
+        got := ""
+        switch obj := evaluated.(type) {
+        case *object.Error:
+            got = obj.Trace()
+        case nil:
+        default:
+            got = obj.Inspect()
+        }
+
+        if got != tt.expected {
+            t.Errorf("wrong result for %q, expected=%q, got=%q", tt.input, tt.expected, got)
+        }
+    }
+}
+
+func TestFunctionParameters(t *testing.T) {
+    tests := []struct {
+        input       string
+        expected    interface{}
+    }{
+        {"let f = func(a, b = 2) { a + b }; f(1);", 3},
+        {"let f = func(a, b = 2) { a + b }; f(1, 5);", 6},
+        {"let f = func(a, b = a * 10) { a + b }; f(1);", 11},
+        {"let f = func(first, ...rest) { len(rest) }; f(1, 2, 3);", 2},
+        {"let f = func(first, ...rest) { len(rest) }; f(1);", 0},
+        {"let f = func(...rest) { rest[1] }; f(1, 2, 3);", 2},
+        {"let add = func(a, b, c) { a + b + c }; add(...[1, 2, 3]);", 6},
+        {"let add = func(a, b, c) { a + b + c }; add(1, ...[2, 3]);", 6},
+        {"len(...[[1, 2]]);", 2},
+        {"let f = func(a, b) { a + b }; f(1);", "expected 2 arguments, got 1"},
+        {"let f = func(a, b) { a + b }; f(1, 2, 3);", "expected 2 arguments, got 3"},
+        {"let f = func(a, b = 2) { a + b }; f();", "expected 1 to 2 arguments, got 0"},
+        {"let f = func(a, ...rest) { a }; f();", "expected at least 1 arguments, got 0"},
+        {"let f = func(a) { a }; f(...1);", "cannot spread INTEGER, expected ARRAY"},
+        {"[...[1]]", "spread operator is only allowed in call arguments"},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(tt.input)
+
+        switch expected := tt.expected.(type) {
+        case int:
+            testIntegerObject(t, evaluated, int64(expected))
+        case string:
+            errObj, ok := evaluated.(*object.Error)
+            if !ok {
+                t.Errorf("object is not error, got=%T (%+v)", evaluated, evaluated)
+                continue
+            }
+
+            if errObj.Message != expected {
+                t.Errorf("wrong error message, expected=%q, got=%q", expected, errObj.Message)
+            }
+        }
+    }
+}
+
+func TestKeywordArguments(t *testing.T) {
+    tests := []struct {
+        input       string
+        expected    interface{}
+    }{
+        {"let f = func(a, b) { a - b }; f(b: 1, a: 5);", 4},
+        {"let f = func(a, b) { a - b }; f(5, b: 1);", 4},
+        {"let f = func(a, b = 2, c = 3) { a + b * c }; f(1, c: 10);", 21},
+        {"let f = func(a, ...rest) { a + len(rest) }; f(a: 1);", 1},
+        {"let f = func(a, b) { a }; f(1, c: 2);", "unexpected keyword argument c"},
+        {"let f = func(a, b) { a }; f(1, a: 2);", "multiple values for argument a"},
+        {"let f = func(a, b = 2) { a }; f(b: 3);", "missing argument for parameter a"},
+        {"let f = func(a) { a }; f(1, ...[2], a: 3);", "multiple values for argument a"},
+        {"len([1], verbose: true);", "builtin function does not accept keyword arguments"},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(tt.input)
+
+        switch expected := tt.expected.(type) {
+        case int:
+            testIntegerObject(t, evaluated, int64(expected))
+        case string:
+            errObj, ok := evaluated.(*object.Error)
+            if !ok {
+                t.Errorf("object is not error, got=%T (%+v)", evaluated, evaluated)
+                continue
+            }
+
+            if errObj.Message != expected {
+                t.Errorf("wrong error message, expected=%q, got=%q", expected, errObj.Message)
+            }
+        }
+    }
+}
+
+func TestSliceExpressions(t *testing.T) {
+    tests := []struct {
+        input       string
+        expected    string
+    }{
+        {"[1, 2, 3, 4, 5][1:3]", "[2, 3]"},
+        {"[1, 2, 3, 4, 5][:2]", "[1, 2]"},
+        {"[1, 2, 3, 4, 5][2:]", "[3, 4, 5]"},
+        {"[1, 2, 3, 4, 5][:]", "[1, 2, 3, 4, 5]"},
+        {"[1, 2, 3, 4, 5][::2]", "[1, 3, 5]"},
+        {"[1, 2, 3, 4, 5][1:4:2]", "[2, 4]"},
+        {"[1, 2, 3, 4, 5][-2:]", "[4, 5]"},
+        {"[1, 2, 3, 4, 5][::-1]", "[5, 4, 3, 2, 1]"},
+        {"[1, 2, 3, 4, 5][10:]", "[]"},
+        {"[1, 2, 3, 4, 5][-10:2]", "[1, 2]"},
+        {`"Hello World"[0:5]`, "Hello"},
+        {`"Hello World"[6:]`, "World"},
+        {`"Hello"[::-1]`, "olleH"},
+        {`"Hello"[0]`, "H"},
+        {`"Hello"[-1]`, "o"},
+        {`"Hello"[5]`, "null"},
+        {`let s = "héllo"; [s[1], len(s), s[0:2]]`, `[é, 5, hé]`},
+        {`"héllo"[-4]`, "é"},
+        {`"日本語"[::-1]`, "語本日"},
+        {`"日本語"[1:]`, "本語"},
+        {"[1, 2, 3][1::9223372036854775807]", "[2]"},
+        {"[1, 2, 3][::-9223372036854775807]", "[3]"},
+        {"[1, 2, 3][1:9223372036854775807:9223372036854775807]", "[2]"},
+        {"[1, 2, 3][-1:-9223372036854775807:-9223372036854775807]", "[3]"},
+        {"[1, 2, 3][::-9223372036854775807 - 1]", "[3]"},
+        {`"héllo"[1:9223372036854775807:9223372036854775807]`, "é"},
+        {`"héllo"[::-9223372036854775807]`, "o"},
+        {`"héllo"[::-9223372036854775807 - 1]`, "o"},
+        {"[1, 2][::0]", "ERROR:slice step cannot be zero"},
+        {`[1, 2]["a":]`, "ERROR:slice indices must be INTEGER, got STRING"},
+        {"5[1:]", "ERROR:slice operator not supported: INTEGER"},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(tt.input)
+
+        if evaluated.Inspect() != tt.expected {
+            t.Errorf("wrong result for %q, expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+        }
+    }
+}
+
+func TestPropertyExpressions(t *testing.T) {
+    tests := []struct {
+        input       string
+        expected    interface{}
+    }{
+        {`let person = {"age": 30}; person.age;`, 30},
+        {`let a = {"b": {"c": 5}}; a.b.c;`, 5},
+        {`let person = {"age": 30}; person.name;`, nil},
+        {`let counter = {"n": 2, "double": func() { self.n * 2 }}; counter.double();`, 4},
+        {`let calc = {"base": 10, "add": func(x) { self.base + x }}; calc.add(5);`, 15},
+        {`let calc = {"add": func(self) { self }}; calc.add(7);`, 7},
+        {`let m = {"len": len}; m.len([1, 2, 3]);`, 3},
+        {`5.foo`, "property access not supported: INTEGER"},
+        {`let o = {"n": 1}; o.n();`, "not a function: INTEGER"},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(tt.input)
+
+        switch expected := tt.expected.(type) {
+        case int:
+            testIntegerObject(t, evaluated, int64(expected))
+        case string:
+            errObj, ok := evaluated.(*object.Error)
+            if !ok {
+                t.Errorf("object is not error, got=%T (%+v)", evaluated, evaluated)
+                continue
+            }
+
+            if errObj.Message != expected {
+                t.Errorf("wrong error message, expected=%q, got=%q", expected, errObj.Message)
+            }
+        default:
+            testNullObject(t, evaluated)
+        }
+    }
+}
+
+func TestMatchExpressions(t *testing.T) {
+    tests := []struct {
+        input       string
+        expected    interface{}
+    }{
+        {`match (1) { 1 => 10, _ => 20 }`, 10},
+        {`match (2) { 1 => 10, _ => 20 }`, 20},
+        {`match ("b") { "a" => 1, "b" => 2, _ => 3 }`, 2},
+        {`match (true) { false => 1, true => 2 }`, 2},
+        {`match (-3) { -3 => 1, _ => 2 }`, 1},
+        {`match (5) { n => n * 2 }`, 10},
+        {`match ([1, 2, 3]) { [a, b] => 0, [a, b, c] => a + b + c }`, 6},
+        {`match ([1, 2, 3]) { [first, ...rest] => len(rest) }`, 2},
+        {`match ([1, [2, 3]]) { [a, [b, c]] => a + b + c }`, 6},
+        {`match ([]) { [x, ...xs] => 1, [] => 2 }`, 2},
+        {`match ({"name": "Jo", "age": 40}) { {age} => age }`, 40},
+        {`match ({"kind": "circle", "r": 2}) { {"kind": "square", "s": s} => s, {"kind": "circle", r} => r * 3 }`, 6},
+        {`match ({"a": 1}) { {b} => 1, _ => 2 }`, 2},
+        {`match (7) { n if n > 10 => 1, n if n > 5 => 2, _ => 3 }`, 2},
+        {`let x = 1; match (5) { x => x }; x;`, 1},
+        {`match (3) { 1 => 1, 2 => 2 }`, "non-exhaustive match: no pattern matched 3"},
+        {`match (1) { n if n + true => 1 }`, "type mismatch: INTEGER + BOOLEAN"},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(tt.input)
+
+        switch expected := tt.expected.(type) {
+        case int:
+            testIntegerObject(t, evaluated, int64(expected))
+        case string:
+            errObj, ok := evaluated.(*object.Error)
+            if !ok {
+                t.Errorf("object is not error, got=%T (%+v)", evaluated, evaluated)
+                continue
+            }
+
+            if errObj.Message != expected {
+                t.Errorf("wrong error message, expected=%q, got=%q", expected, errObj.Message)
+            }
+        }
+    }
+}
+
+func TestDestructuringLetStatements(t *testing.T) {
+    tests := []struct {
+        input       string
+        expected    interface{}
+    }{
+        {"let [a, b] = [1, 2]; a + b;", 3},
+        {"let [a, b, ...rest] = [1, 2, 3, 4]; len(rest);", 2},
+        {"let [a, ...rest] = [1]; len(rest);", 0},
+        {"let [a, [b, c]] = [1, [2, 3]]; a + b + c;", 6},
+        {"let [a, b = 10] = [1]; a + b;", 11},
+        {"let [a, b = a * 2] = [4]; b;", 8},
+        {`let {name, age} = {"name": "Jo", "age": 40}; age;`, 40},
+        {`let {age: years} = {"age": 40}; years;`, 40},
+        {`let {age = 18} = {}; age;`, 18},
+        {`let {pos: [x, y]} = {"pos": [3, 4]}; x * y;`, 12},
+        {`let [_, second] = [1, 2]; second;`, 2},
+        {"let [a, b] = 5;", "cannot destructure 5: pattern [a, b] expected ARRAY, got INTEGER"},
+        {"let [a, b] = [1, 2, 3];", "cannot destructure [1, 2, 3]: pattern [a, b] expected at most 2 elements, got 3"},
+        {"let [a, b] = [1];", "cannot destructure [1]: pattern [a, b] expected at least 2 elements, got 1"},
+        {"let [a, [b, c]] = [1, [2]];", "cannot destructure [1, [2]]: pattern [b, c] expected at least 2 elements, got 1"},
+        {`let {name} = {"age": 1};`, `cannot destructure {age: 1}: pattern {name} is missing key "name"`},
+        {`let {pos: {x}} = {"pos": 1};`, "cannot destructure {pos: 1}: pattern {x} expected HASH, got INTEGER"},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(tt.input)
+
+        switch expected := tt.expected.(type) {
+        case int:
+            testIntegerObject(t, evaluated, int64(expected))
+        case string:
+            errObj, ok := evaluated.(*object.Error)
+            if !ok {
+                t.Errorf("object is not error, got=%T (%+v)", evaluated, evaluated)
+                continue
+            }
+
+            if errObj.Message != expected {
+                t.Errorf("wrong error message, expected=%q, got=%q", expected, errObj.Message)
+            }
+        }
+    }
+}
+
+func TestTryCatchFinally(t *testing.T) {
+    tests := []struct {
+        input       string
+        expected    interface{}
+    }{
+        {`try { 1 } catch (e) { 2 }`, 1},
+        {`try { throw "boom"; 1 } catch (e) { 2 }`, 2},
+        {`try { throw "boom"; } catch (e) { e.message }`, "boom"},
+        {`try { throw "boom"; } catch (e) { e.kind }`, "Error"},
+        {`try { throw 42; } catch (e) { e.payload }`, 42},
+        {`try { throw {"kind": "ValueError", "message": "bad"}; } catch (e) { e.kind + ": " + e.message }`, "ValueError: bad"},
+        {`try { 5 + true; } catch (e) { e.kind }`, "TypeError"},
+        {`try { 5 + true; } catch (e) { e.message }`, "type mismatch: INTEGER + BOOLEAN"},
+        {`try { foobar; } catch (e) { e.kind }`, "NameError"},
+        {`try { func(a) { a }(); } catch (e) { e.kind }`, "ArgumentError"},
+        {`let f = func() { throw "inner"; }; try { f(); } catch (e) { e.message }`, "inner"},
+        {`let x = 0; try { x } finally { 99 }`, 0},
+        {`let f = func() { try { return 1; } finally { 2 } }; f();`, 1},
+        {`let f = func() { try { return 1; } finally { return 2; } }; f();`, 2},
+        {`try { throw "a"; } catch (e) { throw "b"; }`, "ERROR:b"},
+        {`try { throw "a"; } finally { 1 }`, "ERROR:a"},
+        {`try { 1 } finally { throw "f"; }`, "ERROR:f"},
+        {`try { throw "a"; } catch { 3 }`, 3},
+        {`throw "uncaught"; 5;`, "ERROR:uncaught"},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(tt.input)
+
+        switch expected := tt.expected.(type) {
+        case int:
+            testIntegerObject(t, evaluated, int64(expected))
+        case string:
+            if evaluated == nil || evaluated.Inspect() != expected {
+                t.Errorf("wrong result for %q, expected=%q, got=%v", tt.input, expected, evaluated)
+            }
+        }
+    }
+}
+
+func TestDeferStatements(t *testing.T) {
+    fail := `let fail = func(msg) { throw msg; };`
+
+    tests := []struct {
+        input       string
+        expected    interface{}
+    }{
+        {"let f = func() { defer 5; 1 }; f();", 1},
+        {"let f = func() { defer 5; return 2; 1 }; f();", 2},
+        {fail + `let f = func() { defer fail("deferred"); 1 }; f();`, "ERROR:deferred"},
+        {fail + `let f = func() { defer fail("deferred"); return 1; }; f();`, "ERROR:deferred"},
+        {fail + `let f = func() { defer fail("first"); defer fail("second"); 1 }; f();`, "ERROR:second"},
+        {fail + `let f = func() { defer fail("deferred"); throw "original"; }; f();`, "ERROR:original"},
+        {fail + `let f = func() { defer fail("deferred"); 5 + true; }; f();`, "ERROR:type mismatch: INTEGER + BOOLEAN"},
+        {fail + `let f = func(x) { if (x) { defer fail("in block"); } 1 }; f(true);`, "ERROR:in block"},
+        {fail + `let f = func() { let g = func() { defer fail("inner"); 1 }; try { g() } catch (e) { 7 } }; f();`, 7},
+        {"defer 1;", "ERROR:defer is only allowed inside a function"},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(tt.input)
+
+        switch expected := tt.expected.(type) {
+        case int:
+            testIntegerObject(t, evaluated, int64(expected))
+        case string:
+            if evaluated == nil || evaluated.Inspect() != expected {
+                t.Errorf("wrong result for %q, expected=%q, got=%v", tt.input, expected, evaluated)
+            }
+        }
+    }
+}
+
+func TestConstAndStrictMode(t *testing.T) {
+    tests := []struct {
+        input       string
+        expected    interface{}
+    }{
+        {"const x = 5; x;", 5},
+        {"const [a, b] = [1, 2]; a + b;", 3},
+        {"const x = 5; let f = func() { let x = 1; x }; f() + x;", 6},
+        {"let x = 1; let x = 2; x;", 2},
+        {"let len = func(x) { 42 }; len([1]);", 42},
+        {`"use strict"; let x = 1; let f = func() { let x = 2; x }; f();`, 2},
+        {`"use strict"; let x = 1; let x = 2;`, "x is already declared in this scope"},
+        {`"use strict"; let x = 1; func x() {}`, "x is already declared in this scope"},
+        {`"use strict"; let [a, b] = [1, 2]; let {a} = {"a": 3};`, "a is already declared in this scope"},
+        {`"use strict"; let len = 1;`, "cannot shadow builtin len"},
+        {`"use strict"; let f = func(first) { first }; f(1);`, "cannot shadow builtin first"},
+        {`"use strict"; let f = func() { let push = 1; }; f();`, "cannot shadow builtin push"},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(tt.input)
+
+        switch expected := tt.expected.(type) {
+        case int:
+            testIntegerObject(t, evaluated, int64(expected))
+        case string:
+            errObj, ok := evaluated.(*object.Error)
+            if !ok {
+                t.Errorf("object is not error for %q, got=%T (%+v)", tt.input, evaluated, evaluated)
+                continue
+            }
+
+            if errObj.Message != expected {
+                t.Errorf("wrong error message, expected=%q, got=%q", expected, errObj.Message)
+            }
+        }
+    }
+}
+
+func TestConstReassignmentAtRuntime(t *testing.T) {
+    env := object.NewEnvironment()
+
+    for _, input := range []string{"const x = 1;", "let x = 2;"} {
+        l := lexer.New(input)
+        p := parser.New(l)
+        evaluated := Eval(p.ParseProgram(), env)
+
+        if input == "let x = 2;" {
+            errObj, ok := evaluated.(*object.Error)
+            if !ok {
+                t.Fatalf("object is not error, got=%T (%+v)", evaluated, evaluated)
+            }
+
+            if errObj.Message != "cannot reassign constant x" {
+                t.Errorf("wrong error message, got=%q", errObj.Message)
+            }
+        }
+    }
+}
+
+func TestStrictModeEnvironment(t *testing.T) {
+    env := object.NewEnvironment()
+    env.SetStrict(true)
+
+    l := lexer.New("let x = 1; let x = 2;")
+    p := parser.New(l)
+    evaluated := Eval(p.ParseProgram(), env)
+
+    errObj, ok := evaluated.(*object.Error)
+    if !ok {
+        t.Fatalf("object is not error, got=%T (%+v)", evaluated, evaluated)
+    }
+
+    if errObj.Message != "x is already declared in this scope" {
+        t.Errorf("wrong error message, got=%q", errObj.Message)
+    }
+}
+
+func TestNullCoalescingAndOptionalChaining(t *testing.T) {
+    tests := []struct {
+        input       string
+        expected    interface{}
+    }{
+        {`{"a": 1}["a"] ?? 2`, 1},
+        {`{"a": 1}["b"] ?? 2`, 2},
+        {`[1, 2][5] ?? 3`, 3},
+        {`false ?? 4`, "false"},
+        {`{}["x"] ?? {}["y"] ?? 5`, 5},
+        {`1 ?? foobar`, 1},
+        {`{}["x"] ?? foobar`, "ERROR:identifier not found: foobar"},
+        {`let p = {"a": {"b": 7}}; p?.a?.b`, 7},
+        {`let p = {"a": {"b": 7}}; p.x?.b`, nil},
+        {`let p = {"a": {"b": 7}}; p.x?.b.c.d`, nil},
+        {`let p = {"a": [1, 2]}; p.a?.[1]`, 2},
+        {`let p = {}; p.a?.[foobar]`, nil},
+        {`let u = {}; u["x"]?.a[1:2]`, nil},
+        {`let u = {"x": {"a": [1, 2, 3]}}; u["x"]?.a[1:2]`, "[2]"},
+        {`let u = {}; u.x[1:]`, "ERROR:slice operator not supported: NULL"},
+        {`let p = {"a": {"b": 7}}; p.x?.b ?? 9`, 9},
+        {`let p = {}; p.x?.f(foobar)`, nil},
+        {`let p = {"o": {"f": func() { self.v }, "v": 3}}; p.o?.f()`, 3},
+        {`let p = {}; p.n?.f(1).x`, nil},
+        {`let p = {}; p.n?.f(1)(2)[0].x`, nil},
+        {`let p = {"f": func(x) { {"x": x} }}; p?.f(1).x`, 1},
+        {`let p = {}; p.a?.[1:2]`, nil},
+        {`let p = {"a": [1, 2, 3]}; p.a?.[1:]`, "[2, 3]"},
+        {`let p = {}; p.a?.[:1].b`, nil},
+        {`let p = {}; p.x.b`, "ERROR:property access not supported: NULL"},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(tt.input)
+
+        switch expected := tt.expected.(type) {
+        case int:
+            testIntegerObject(t, evaluated, int64(expected))
+        case string:
+            if evaluated == nil || evaluated.Inspect() != expected {
+                t.Errorf("wrong result for %q, expected=%q, got=%v", tt.input, expected, evaluated)
+            }
+        default:
+            testNullObject(t, evaluated)
+        }
+    }
+}
+
+func TestPipelineExpressions(t *testing.T) {
+    tests := []struct {
+        input       string
+        expected    interface{}
+    }{
+        {"let sub = func(a, b) { a - b }; 10 |> sub(3);", 7},
+        {"let double = func(x) { x * 2 }; 5 |> double;", 10},
+        {"let double = func(x) { x * 2 }; 5 |> double();", 10},
+        {"let add = func(a, b) { a + b }; let double = func(x) { x * 2 }; 1 |> add(2) |> double;", 6},
+        {"[1, 2, 3] |> push(4) |> len;", 4},
+        {"[1, 2, 3] |> len();", 3},
+        {`let m = {"k": 10, "add": func(x) { self.k + x }}; 5 |> m.add();`, 15},
+        {"let f = func(a, b = 1) { a * b }; 4 |> f(b: 3);", 12},
+        {"5 |> 3;", "cannot pipe into INTEGER: (5 |> 3)"},
+        {"let f = func(a, b, c) { a }; 1 |> f(2);", "(1 |> f(2)): expected 3 arguments, got 2"},
+        {"let f = func() { 1 }; 1 |> f;", "(1 |> f): expected 0 arguments, got 1"},
+        {`"a" |> push(1);`, "(a |> push(1)): argument to `first` must be ARRAY, got=STRING"},
+        {`let f = func(x) { throw "no" }; 1 |> f;`, "no"},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(tt.input)
+
+        switch expected := tt.expected.(type) {
+        case int:
+            testIntegerObject(t, evaluated, int64(expected))
+        case string:
+            errObj, ok := evaluated.(*object.Error)
+            if !ok {
+                t.Errorf("object is not error, got=%T (%+v)", evaluated, evaluated)
+                continue
+            }
+
+            if errObj.Message != expected {
+                t.Errorf("wrong error message, expected=%q, got=%q", expected, errObj.Message)
+            }
+        }
+    }
+}
+
+func TestArrowFunctions(t *testing.T) {
+    tests := []struct {
+        input       string
+        expected    int64
+    }{
+        {"let double = x => x * 2; double(4);", 8},
+        {"let add = (a, b) => a + b; add(2, 3);", 5},
+        {"let five = () => 5; five();", 5},
+        {"let f = (x) => { let y = x * 2; y + 1 }; f(3);", 7},
+        {"let f = (x) => { return x; 99 }; f(3);", 3},
+        {"let adder = x => y => x + y; adder(2)(3);", 5},
+        {"let apply = func(f, v) { f(v) }; apply(x => x - 1, 10);", 9},
+        {"3 |> (x => x * x);", 9},
+        {"let f = (a, b = 10) => a + b; f(1);", 11},
+    }
+
+    for _, tt := range tests {
+        testIntegerObject(t, testEval(tt.input), tt.expected)
+    }
+}
+
+func TestRanges(t *testing.T) {
+    tests := []struct {
+        input       string
+        expected    string
+    }{
+        {"1..10", "1..10"},
+        {"let n = 5; 0..<n", "0..<5"},
+        {"len(1..10)", "10"},
+        {"len(1..<10)", "9"},
+        {"len(5..1)", "0"},
+        {"(1..10)[0]", "1"},
+        {"(1..10)[9]", "10"},
+        {"(1..10)[10]", "null"},
+        {"(1..<10)[-1]", "9"},
+        {"(1..<10)[-9]", "1"},
+        {"(1..<10)[-10]", "null"},
+        {"(0..9223372036854775807)[-1]", "9223372036854775807"},
+        {"(-9223372036854775807..9223372036854775807)[-1]", "9223372036854775807"},
+        {"(-9223372036854775807..9223372036854775807)[-9223372036854775807]", "1"},
+        {"(-9223372036854775807..9223372036854775807)[9223372036854775807]", "0"},
+        {"5 in 1..10", "true"},
+        {"10 in 1..<10", "false"},
+        {`"a" in 1..10`, "false"},
+        {"array(1..4)", "[1, 2, 3, 4]"},
+        {"array(0..<0)", "[]"},
+        {`array("abc")`, "[a, b, c]"},
+        {`array("héllo")`, "[h, é, l, l, o]"},
+        {`array({"b": 1, "a": 2, "c": 3})`, "[b, a, c]"},
+        {`{"b": 1, "a": 2, "b": 3}`, "{b: 3, a: 2}"},
+        {"array(1)", "ERROR:INTEGER is not iterable"},
+        {`1.."a"`, "ERROR:range bounds must be INTEGER, got INTEGER..STRING"},
+        {"2 in [1, 2, 3]", "true"},
+        {`"ell" in "hello"`, "true"},
+        {`"a" in {"a": 1}`, "true"},
+        {`"b" in {"a": 1}`, "false"},
+        {"1 in 5", "ERROR:unknown operator: INTEGER in INTEGER"},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(tt.input)
+
+        if evaluated == nil || evaluated.Inspect() != tt.expected {
+            t.Errorf("wrong result for %q, expected=%q, got=%v", tt.input, tt.expected, evaluated)
+        }
+    }
+}
+
+func TestComprehensions(t *testing.T) {
+    tests := []struct {
+        input       string
+        expected    string
+    }{
+        {"[x * 2 for x in [1, 2, 3]]", "[2, 4, 6]"},
+        {"[x * 2 for x in [-1, 2, -3, 4] if x > 0]", "[4, 8]"},
+        {"[x for x in 1..<0]", "[]"},
+        {"[i * x for i, x in [5, 6, 7]]", "[0, 6, 14]"},
+        {`[c for c in "abc"]`, "[a, b, c]"},
+        {"let n = 10; [x + n for x in 1..3]", "[11, 12, 13]"},
+        {`{k: v * 2 for k, v in {"a": 1}}["a"]`, "2"},
+        {`{x: true for x in [1, 1, 2]}[2]`, "true"},
+        {`{x: x * x for x in 1..5 if x > 3}[5]`, "25"},
+        {`[k + v for k, v in {"c": "1", "a": "2", "b": "3"}]`, "[c1, a2, b3]"},
+        {`{v: k for k, v in {"c": "x", "a": "y", "b": "z"}}`, "{x: c, y: a, z: b}"},
+        {`{x: x for x in ["b", "a", "b", "c"]}`, "{b: b, a: a, c: c}"},
+        {`let x = 1; [x for x in 5..6]; x`, "1"},
+        {"let fs = [func() { x } for x in 1..3]; fs[0]()", "1"},
+        {"[x for x in 5]", "ERROR:INTEGER is not iterable"},
+        {"[y for x in [1]]", "ERROR:identifier not found: y"},
+        {"{[x]: x for x in [1]}", "ERROR:unusable hash key: ARRAY"},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(tt.input)
+
+        if evaluated == nil || evaluated.Inspect() != tt.expected {
+            t.Errorf("wrong result for %q, expected=%q, got=%v", tt.input, tt.expected, evaluated)
+        }
+    }
+}
+
+func writeModules(t *testing.T, files map[string]string) string {
+    dir := t.TempDir()
+
+    for name, src := range files {
+        path := filepath.Join(dir, name)
+        if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+            t.Fatal(err)
+        }
+        if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    return dir
+}
+
+func testEvalIn(input, dir string) object.Object {
+    l := lexer.New(input)
+    p := parser.New(l)
+    program := p.ParseProgram()
+    env := object.NewEnvironment()
+    env.SetDir(dir)
+
+    return Eval(program, env)
+}
+
+func TestImports(t *testing.T) {
+    dir := writeModules(t, map[string]string{
+        "lib/math.ell": `let hidden = 1; export const pi = 3; export func square(x) { x * x }`,
+        "lib/geometry.ell": `import "math" as m; export func area(r) { m.pi * m.square(r) }`,
+        "search/util.ell": `export let [one, two] = [1, 2];`,
+        "broken.ell": `let = 1;`,
+        "a.ell": `import "b" as b;`,
+        "b.ell": `import "a" as a;`,
+        "inner.ell": `func f() { export let x = 1; } f();`,
+    })
+    t.Setenv("ELLIOTT_PATH", filepath.Join(dir, "search"))
+
+    tests := []struct {
+        input       string
+        expected    string
+    }{
+        {`import "lib/math" as m; m.square(m.pi)`, "9"},
+        {`import "lib/math.ell" as m; m.pi`, "3"},
+        {`import "lib/geometry" as g; g.area(2)`, "12"},
+        {`import "lib/math" as m; import "lib/math" as n; m == n`, "true"},
+        {`import "util" as u; u.one + u.two`, "3"},
+        {`import "lib/math" as m; m.hidden`, "ERROR:module " + filepath.Join(dir, "lib/math.ell") + " does not export hidden"},
+        {`import "missing" as m;`, "ERROR:module \"missing\" not found"},
+        {`import "broken" as m;`, "ERROR:cannot import " + filepath.Join(dir, "broken.ell") + ": parse errors"},
+        {`import "a" as a;`, "ERROR:import cycle: " + filepath.Join(dir, "a.ell") + " -> " +
+            filepath.Join(dir, "b.ell") + " -> " + filepath.Join(dir, "a.ell")},
+        {`import "inner" as m;`, "ERROR:export is only allowed at the top level of a module"},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEvalIn(tt.input, dir)
+
+        if evaluated == nil || !strings.HasPrefix(evaluated.Inspect(), tt.expected) {
+            t.Errorf("wrong result for %q, expected=%q, got=%v", tt.input, tt.expected, evaluated)
+        }
+    }
+}
+
+func TestImportCycleIncludesEntry(t *testing.T) {
+    dir := writeModules(t, map[string]string{
+        "main.ell": `import "a" as a;`,
+        "a.ell": `import "b" as b;`,
+        "b.ell": `import "a" as a;`,
+        "c.ell": `import "main" as m;`,
+    })
+
+    tests := []struct {
+        input       string
+        expected    string
+    }{
+        {`import "a" as a;`, "import cycle: " + filepath.Join(dir, "main.ell") + " -> " + filepath.Join(dir, "a.ell") +
+            " -> " + filepath.Join(dir, "b.ell") + " -> " + filepath.Join(dir, "a.ell")},
+        {`import "c" as c;`, "import cycle: " + filepath.Join(dir, "main.ell") + " -> " + filepath.Join(dir, "c.ell") +
+            " -> " + filepath.Join(dir, "main.ell")},
+    }
+
+    for _, tt := range tests {
+        loader := object.NewLoader()
+        loader.Loading = []string{filepath.Join(dir, "main.ell")}
+
+        program := parser.New(lexer.New(tt.input)).ParseProgram()
+        env := object.NewModuleEnvironment(nil)
+        env.SetDir(dir)
+        env.SetLoader(loader)
+
+        evaluated := Eval(program, env)
+        errObj, ok := evaluated.(*object.Error)
+        if !ok || errObj.Message != tt.expected {
+            t.Errorf("wrong result for %q, expected=%q, got=%v", tt.input, tt.expected, evaluated)
+        }
+    }
+}
+
+func TestImportsArePerRun(t *testing.T) {
+    dir := writeModules(t, map[string]string{
+        "counter.ell": `export let n = 1;`,
+        "user.ell": `import "counter" as c; export let m = c;`,
+    })
+
+    if got := testEvalIn(`import "counter" as c; import "user" as u; c == u.m`, dir); got != TRUE {
+        t.Errorf("a module imported twice in one run should be loaded once, got=%v", got)
+    }
+
+    if err := os.WriteFile(filepath.Join(dir, "counter.ell"), []byte(`export let n = 2;`), 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    if got := testEvalIn(`import "counter" as c; c.n`, dir); got.Inspect() != "2" {
+        t.Errorf("a new run should load the module again, got=%v", got.Inspect())
+    }
+}
+
+func TestQuoteUnquote(t *testing.T) {
+    tests := []struct {
+        input       string
+        expected    string
+    }{
+        {"quote(5)", "5"},
+        {"quote(5 + 8)", "(5 + 8)"},
+        {"quote(foobar + barfoo)", "(foobar + barfoo)"},
+        {"quote(unquote(4))", "4"},
+        {"quote(8 + unquote(4 + 4))", "(8 + 8)"},
+        {"quote(unquote(4 + 4) + 8)", "(8 + 8)"},
+        {"let foobar = 8; quote(unquote(foobar))", "8"},
+        {"quote(unquote(true == false))", "false"},
+        {`quote(unquote("a") + b)`, "(a + b)"},
+        {"quote(unquote([1, 2]))", "[1, 2]"},
+        {"quote(unquote(quote(4 + 4)))", "(4 + 4)"},
+        {"let quotedInfixExpression = quote(4 + 4); quote(unquote(4 + 4) + unquote(quotedInfixExpression))", "(8 + (4 + 4))"},
+        {"let q = func(x) { quote(unquote(x) + 1) }; q(1); q(2)", "(2 + 1)"},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(tt.input)
+
+        quote, ok := evaluated.(*object.Quote)
+        if !ok {
+            t.Fatalf("expected *object.Quote for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+        }
+
+        if quote.Node == nil {
+            t.Fatalf("quote.Node is nil")
+        }
+
+        if quote.Node.String() != tt.expected {
+            t.Errorf("not equal. got=%q, want=%q", quote.Node.String(), tt.expected)
+        }
+    }
+
+    errors := []struct {
+        input       string
+        expected    string
+    }{
+        {"quote(1, 2)", "quote expects 1 argument, got 2"},
+        {"quote(unquote(func() { 1 }))", "cannot unquote FUNCTION"},
+        {"quote(unquote(missing))", "identifier not found: missing"},
+    }
+
+    for _, tt := range errors {
+        evaluated := testEval(tt.input)
+
+        errObj, ok := evaluated.(*object.Error)
+        if !ok {
+            t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+            continue
+        }
+
+        if errObj.Message != tt.expected {
+            t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+        }
+    }
+}
+
+func TestDefineMacros(t *testing.T) {
+    input := `
+    let number = 1;
+    let function = func(x, y) { x + y };
+    let mymacro = macro(x, y) { x + y; };
+    `
+
+    env := object.NewEnvironment()
+    program := testParseProgram(input)
+
+    DefineMacros(program, env)
+
+    if len(program.Statements) != 2 {
+        t.Fatalf("Wrong number of statements. got=%d", len(program.Statements))
+    }
+
+    if _, ok := env.Get("number"); ok {
+        t.Fatalf("number should not be defined")
+    }
+    if _, ok := env.Get("function"); ok {
+        t.Fatalf("function should not be defined")
+    }
+
+    obj, ok := env.Get("mymacro")
+    if !ok {
+        t.Fatalf("macro not in environment.")
+    }
+
+    macro, ok := obj.(*object.Macro)
+    if !ok {
+        t.Fatalf("object is not Macro. got=%T (%+v)", obj, obj)
+    }
+
+    if len(macro.Parameters) != 2 {
+        t.Fatalf("Wrong number of macro parameters. got=%d", len(macro.Parameters))
+    }
+
+    if macro.Body.String() != "(x + y)" {
+        t.Fatalf("body is not %q. got=%q", "(x + y)", macro.Body.String())
+    }
+}
+
+func TestExpandMacros(t *testing.T) {
+    tests := []struct {
+        input       string
+        expected    string
+    }{
+        {
+            `let infixExpression = macro() { quote(1 + 2); }; infixExpression();`,
+            `(1 + 2)`,
+        },
+        {
+            `let reverse = macro(a, b) { quote(unquote(b) - unquote(a)); }; reverse(2 + 2, 10 - 5);`,
+            `(10 - 5) - (2 + 2)`,
+        },
+        {
+            `let unless = macro(condition, consequence, alternative) {
+                quote(if (!(unquote(condition))) {
+                    unquote(consequence);
+                } else {
+                    unquote(alternative);
+                });
+            };
+            unless(10 > 5, print("not greater"), print("greater"));`,
+            `if (!(10 > 5)) { print("not greater") } else { print("greater") }`,
+        },
+        {
+            `let twice = macro(x) { quote([unquote(x), unquote(x)]) }; f(twice(1));`,
+            `f([1, 1])`,
+        },
+        {
+            `let unless = macro(c, a, b) { quote(if (!(unquote(c))) { unquote(a) } else { unquote(b) }) };
+            [unless(true, 1, 2), unless(false, 3, 4)];`,
+            `[if (!(true)) { 1 } else { 2 }, if (!(false)) { 3 } else { 4 }]`,
+        },
+    }
+
+    for _, tt := range tests {
+        expected := testParseProgram(tt.expected)
+        program := testParseProgram(tt.input)
+
+        env := object.NewEnvironment()
+        DefineMacros(program, env)
+        expanded, err := ExpandMacros(program, env)
+        if err != nil {
+            t.Fatalf("unexpected error for %q: %s", tt.input, err.Message)
+        }
+
+        if expanded.String() != expected.String() {
+            t.Errorf("not equal. want=%q, got=%q", expected.String(), expanded.String())
+        }
+    }
+
+    errors := []struct {
+        input       string
+        expected    string
+    }{
+        {`let m = macro(a) { quote(unquote(a)) }; m(1, 2);`, "macro m expects 1 arguments, got 2"},
+        {`let m = macro() { 1 }; m();`, "macro m must return a QUOTE, got INTEGER"},
+        {`let m = macro() { missing }; m();`, "identifier not found: missing"},
+    }
+
+    for _, tt := range errors {
+        program := testParseProgram(tt.input)
+
+        env := object.NewEnvironment()
+        DefineMacros(program, env)
+        _, err := ExpandMacros(program, env)
+
+        if err == nil || err.Message != tt.expected {
+            t.Errorf("wrong error for %q. expected=%q, got=%v", tt.input, tt.expected, err)
+        }
+    }
+}
+
+func testParseProgram(input string) *ast.Program {
+    l := lexer.New(input)
+    p := parser.New(l)
+    return p.ParseProgram()
+}
@@ -0,0 +1,118 @@
+package evaluator
+
+import (
+    "github.com/JakeNorman007/interpreter/ast"
+    "github.com/JakeNorman007/interpreter/object"
+)
+
+// DefineMacros moves every top-level `let name = macro(...) {...}` out of
+// program and into env.
+func DefineMacros(program *ast.Program, env *object.Environment) {
+    definitions := []int{}
+
+    for i, statement := range program.Statements {
+        if isMacroDefinition(statement) {
+            addMacro(statement, env)
+            definitions = append(definitions, i)
+        }
+    }
+
+    for i := len(definitions) - 1; i >= 0; i-- {
+        idx := definitions[i]
+        program.Statements = append(program.Statements[:idx], program.Statements[idx + 1:]...)
+    }
+}
+
+func isMacroDefinition(node ast.Statement) bool {
+    let, ok := node.(*ast.LetStatement)
+    if !ok || let.Name == nil {
+        return false
+    }
+
+    _, ok = let.Value.(*ast.MacroLiteral)
+    return ok
+}
+
+func addMacro(stmt ast.Statement, env *object.Environment) {
+    let := stmt.(*ast.LetStatement)
+    lit := let.Value.(*ast.MacroLiteral)
+
+    env.Set(let.Name.Value, &object.Macro{Parameters: lit.Parameters, Body: lit.Body, Env: env})
+}
+
+// ExpandMacros replaces every call of a macro defined in env with the AST the
+// macro returns. Macro arguments are passed quoted, not evaluated.
+func ExpandMacros(program ast.Node, env *object.Environment) (ast.Node, *object.Error) {
+    var err *object.Error
+
+    expanded := ast.Modify(program, func(node ast.Node) ast.Node {
+        if err != nil {
+            return node
+        }
+
+        call, ok := node.(*ast.CallExpression)
+        if !ok {
+            return node
+        }
+
+        macro, name, ok := isMacroCall(call, env)
+        if !ok {
+            return node
+        }
+
+        if len(call.Arguments) != len(macro.Parameters) {
+            err = newKindError(object.ARGUMENT_ERROR, "macro %s expects %d arguments, got %d",
+                name, len(macro.Parameters), len(call.Arguments))
+            return node
+        }
+
+        evaluated := unwrapReturnValue(Eval(macro.Body, extendMacroEnv(macro, call.Arguments)))
+        if isError(evaluated) {
+            err = evaluated.(*object.Error)
+            return node
+        }
+
+        quote, ok := evaluated.(*object.Quote)
+        if !ok {
+            err = newKindError(object.TYPE_ERROR, "macro %s must return a QUOTE, got %s", name, typeOf(evaluated))
+            return node
+        }
+
+        return ast.Copy(quote.Node)
+    })
+
+    return expanded, err
+}
+
+func isMacroCall(exp *ast.CallExpression, env *object.Environment) (*object.Macro, string, bool) {
+    ident, ok := exp.Function.(*ast.Identifier)
+    if !ok {
+        return nil, "", false
+    }
+
+    obj, ok := env.Get(ident.Value)
+    if !ok {
+        return nil, "", false
+    }
+
+    macro, ok := obj.(*object.Macro)
+    return macro, ident.Value, ok
+}
+
+func extendMacroEnv(macro *object.Macro, args []ast.Expression) *object.Environment {
+    extended := object.NewEnclosedEnvironment(macro.Env)
+
+    for i, param := range macro.Parameters {
+        extended.Set(param.Value, &object.Quote{Node: args[i]})
+    }
+
+    return extended
+}
+
+func typeOf(obj object.Object) object.ObjectType {
+    if obj == nil {
+        return object.NULL_OBJ
+    }
+
+    return obj.Type()
+}
@@ -0,0 +1,135 @@
+package evaluator
+
+import (
+    "os"
+    "path/filepath"
+    "strings"
+    "github.com/JakeNorman007/interpreter/ast"
+    "github.com/JakeNorman007/interpreter/lexer"
+    "github.com/JakeNorman007/interpreter/object"
+    "github.com/JakeNorman007/interpreter/parser"
+)
+
+const moduleExtension = ".ell"
+
+func evalImportStatement(node *ast.ImportStatement, env *object.Environment) object.Object {
+    path, err := resolveModule(node.Path.Value, env.Dir())
+    if err != nil {
+        return err
+    }
+
+    mod, err := loadModule(env.Loader(), path, env.Base())
+    if err != nil {
+        return err
+    }
+
+    if err := declare(env, node.Alias.Value, mod, false); err != nil {
+        return err
+    }
+
+    return nil
+}
+
+func evalExportStatement(node *ast.ExportStatement, env *object.Environment) object.Object {
+    for _, name := range node.Names() {
+        if !env.Export(name) {
+            return newError("export is only allowed at the top level of a module")
+        }
+    }
+
+    return Eval(node.Statement, env)
+}
+
+// resolveModule finds the file an import refers to, trying dir (the importing
+// file's directory) first and then each entry of ELLIOTT_PATH.
+func resolveModule(name, dir string) (string, *object.Error) {
+    file := name
+    if filepath.Ext(file) != moduleExtension {
+        file += moduleExtension
+    }
+
+    candidates := []string{file}
+    if !filepath.IsAbs(file) {
+        candidates = []string{filepath.Join(dir, file)}
+        for _, root := range filepath.SplitList(os.Getenv("ELLIOTT_PATH")) {
+            if root != "" {
+                candidates = append(candidates, filepath.Join(root, file))
+            }
+        }
+    }
+
+    for _, candidate := range candidates {
+        info, err := os.Stat(candidate)
+        if err != nil || info.IsDir() {
+            continue
+        }
+
+        abs, err := filepath.Abs(candidate)
+        if err != nil {
+            return "", newKindError(object.IMPORT_ERROR, "cannot resolve module %q: %s", name, err)
+        }
+        return abs, nil
+    }
+
+    return "", newKindError(object.IMPORT_ERROR, "module %q not found (searched %s)",
+        name, strings.Join(candidates, ", "))
+}
+
+// loadModule evaluates the module at path in its own environment enclosed by
+// base, or returns the module if loader already loaded it.
+func loadModule(loader *object.Loader, path string, base *object.Environment) (*object.Module, *object.Error) {
+    if mod, ok := loader.Modules[path]; ok {
+        return mod, nil
+    }
+
+    for _, loading := range loader.Loading {
+        if loading == path {
+            chain := append(append([]string{}, loader.Loading...), path)
+            return nil, newKindError(object.IMPORT_ERROR, "import cycle: %s", strings.Join(chain, " -> "))
+        }
+    }
+
+    src, err := os.ReadFile(path)
+    if err != nil {
+        return nil, newKindError(object.IMPORT_ERROR, "cannot read module %s: %s", path, err)
+    }
+
+    p := parser.New(lexer.New(string(src)))
+    program := p.ParseProgram()
+    if len(p.Errors()) != 0 {
+        return nil, newKindError(object.IMPORT_ERROR, "cannot import %s: parse errors: %s",
+            path, strings.Join(p.Errors(), "; "))
+    }
+
+    macroEnv := object.NewEnvironment()
+    DefineMacros(program, macroEnv)
+    expanded, macroErr := ExpandMacros(program, macroEnv)
+    if macroErr != nil {
+        return nil, macroErr
+    }
+
+    env := object.NewModuleEnvironment(base)
+    env.SetDir(filepath.Dir(path))
+    env.SetLoader(loader)
+
+    loader.Loading = append(loader.Loading, path)
+    defer func() { loader.Loading = loader.Loading[:len(loader.Loading) - 1] }()
+
+    if result := Eval(expanded, env); isError(result) {
+        return nil, result.(*object.Error)
+    }
+
+    mod := &object.Module{Path: path, Env: env}
+    loader.Modules[path] = mod
+
+    return mod, nil
+}
+
+func evalModuleMember(mod *object.Module, name string) object.Object {
+    if !mod.Env.IsExported(name) {
+        return newKindError(object.NAME_ERROR, "module %s does not export %s", mod.Path, name)
+    }
+
+    val, _ := mod.Env.Get(name)
+    return val
+}
@@ -0,0 +1,200 @@
+package evaluator
+
+import (
+	"fmt"
+	"github.com/JakeNorman007/interpreter/ast"
+	"github.com/JakeNorman007/interpreter/object"
+)
+
+func evalMatchExpression(me *ast.MatchExpression, env *object.Environment) object.Object {
+    subject := Eval(me.Subject, env)
+    if isError(subject) {
+        return subject
+    }
+
+    for _, arm := range me.Arms {
+        armEnv := object.NewEnclosedEnvironment(env)
+
+        mismatch, err := matchPattern(arm.Pattern, subject, armEnv)
+        if err != nil {
+            return err
+        }
+        if mismatch != "" {
+            continue
+        }
+
+        if arm.Guard != nil {
+            guard := Eval(arm.Guard, armEnv)
+            if isError(guard) {
+                return guard
+            }
+            if !isTruthy(guard) {
+                continue
+            }
+        }
+
+        return Eval(arm.Body, armEnv)
+    }
+
+    return newKindError(object.MATCH_ERROR, "non-exhaustive match: no pattern matched %s", subject.Inspect())
+}
+
+func evalDestructuringLet(ls *ast.LetStatement, val object.Object, env *object.Environment) object.Object {
+    // bind into a scratch scope first so every name goes through declare
+    scratch := object.NewEnclosedEnvironment(env)
+
+    mismatch, err := matchPattern(ls.Pattern, val, scratch)
+    if err != nil {
+        return err
+    }
+
+    if mismatch != "" {
+        return newKindError(object.MATCH_ERROR, "cannot destructure %s: %s", val.Inspect(), mismatch)
+    }
+
+    for _, name := range scratch.Names() {
+        bound, _ := scratch.Get(name)
+        if err := declare(env, name, bound, ls.IsConst()); err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+// matchPattern checks value against pattern, binding any identifiers in the
+// pattern into env as it goes. It returns a description of the part of the
+// pattern that didn't match, or "" when the whole pattern matched.
+func matchPattern(pattern ast.Expression, value object.Object, env *object.Environment) (string, object.Object) {
+    switch pattern := pattern.(type) {
+    case *ast.Identifier:
+        if pattern.Value != "_" {
+            env.Set(pattern.Value, value)
+        }
+        return "", nil
+    case *ast.DefaultPattern:
+        return matchPattern(pattern.Pattern, value, env)
+    case *ast.ArrayPattern:
+        return matchArrayPattern(pattern, value, env)
+    case *ast.HashPattern:
+        return matchHashPattern(pattern, value, env)
+    default:
+        literal := Eval(pattern, env)
+        if isError(literal) {
+            return "", literal
+        }
+
+        if !objectsEqual(literal, value) {
+            return fmt.Sprintf("pattern %s expected %s, got %s", pattern, literal.Inspect(), value.Inspect()), nil
+        }
+
+        return "", nil
+    }
+}
+
+func matchArrayPattern(pattern *ast.ArrayPattern, value object.Object, env *object.Environment) (string, object.Object) {
+    arr, ok := value.(*object.Array)
+    if !ok {
+        return fmt.Sprintf("pattern %s expected ARRAY, got %s", pattern, value.Type()), nil
+    }
+
+    required := len(pattern.Elements)
+    for required > 0 {
+        if _, ok := pattern.Elements[required - 1].(*ast.DefaultPattern); !ok {
+            break
+        }
+        required--
+    }
+
+    if len(arr.Elements) < required {
+        return fmt.Sprintf("pattern %s expected at least %d elements, got %d", pattern, required, len(arr.Elements)), nil
+    }
+    if pattern.Rest == nil && len(arr.Elements) > len(pattern.Elements) {
+        return fmt.Sprintf("pattern %s expected at most %d elements, got %d", pattern, len(pattern.Elements), len(arr.Elements)), nil
+    }
+
+    for i, el := range pattern.Elements {
+        if i >= len(arr.Elements) {
+            if err := bindDefault(el.(*ast.DefaultPattern), env); err != nil {
+                return "", err
+            }
+            continue
+        }
+
+        mismatch, err := matchPattern(el, arr.Elements[i], env)
+        if err != nil || mismatch != "" {
+            return mismatch, err
+        }
+    }
+
+    if pattern.Rest != nil {
+        rest := []object.Object{}
+        if len(arr.Elements) > len(pattern.Elements) {
+            rest = append(rest, arr.Elements[len(pattern.Elements):]...)
+        }
+        env.Set(pattern.Rest.Value, &object.Array{Elements: rest})
+    }
+
+    return "", nil
+}
+
+func matchHashPattern(pattern *ast.HashPattern, value object.Object, env *object.Environment) (string, object.Object) {
+    hash, ok := value.(*object.Hash)
+    if !ok {
+        return fmt.Sprintf("pattern %s expected HASH, got %s", pattern, value.Type()), nil
+    }
+
+    for i, key := range pattern.Keys {
+        pair, ok := hash.Pairs[(&object.String{Value: key}).HashKey()]
+        if !ok {
+            dp, ok := pattern.Values[i].(*ast.DefaultPattern)
+            if !ok {
+                return fmt.Sprintf("pattern %s is missing key %q", pattern, key), nil
+            }
+
+            if err := bindDefault(dp, env); err != nil {
+                return "", err
+            }
+            continue
+        }
+
+        mismatch, err := matchPattern(pattern.Values[i], pair.Value, env)
+        if err != nil || mismatch != "" {
+            return mismatch, err
+        }
+    }
+
+    return "", nil
+}
+
+func bindDefault(dp *ast.DefaultPattern, env *object.Environment) object.Object {
+    val := Eval(dp.Default, env)
+    if isError(val) {
+        return val
+    }
+
+    mismatch, err := matchPattern(dp.Pattern, val, env)
+    if err != nil {
+        return err
+    }
+    if mismatch != "" {
+        return newError("default value does not match: %s", mismatch)
+    }
+
+    return nil
+}
+
+func objectsEqual(a, b object.Object) bool {
+    if a.Type() != b.Type() {
+        return false
+    }
+
+    switch a := a.(type) {
+    case *object.Integer:
+        return a.Value == b.(*object.Integer).Value
+    case *object.String:
+        return a.Value == b.(*object.String).Value
+    default:
+        return a == b
+    }
+}
@@ -0,0 +1,112 @@
+package evaluator
+
+import (
+    "fmt"
+    "github.com/JakeNorman007/interpreter/ast"
+    "github.com/JakeNorman007/interpreter/object"
+    "github.com/JakeNorman007/interpreter/token"
+)
+
+func isQuoteCall(node *ast.CallExpression) bool {
+    ident, ok := node.Function.(*ast.Identifier)
+    return ok && ident.Value == "quote"
+}
+
+func isUnquoteCall(node ast.Node) bool {
+    call, ok := node.(*ast.CallExpression)
+    if !ok {
+        return false
+    }
+
+    ident, ok := call.Function.(*ast.Identifier)
+    return ok && ident.Value == "unquote"
+}
+
+// quote returns a copy of its argument unevaluated, except that every
+// unquote(...) call inside it is evaluated in env and spliced back in as an
+// AST node. The argument itself is left alone, so it can be quoted again.
+func quote(args []ast.Expression, env *object.Environment) object.Object {
+    if len(args) != 1 {
+        return newKindError(object.ARGUMENT_ERROR, "quote expects 1 argument, got %d", len(args))
+    }
+
+    var err object.Object
+
+    node := ast.Modify(ast.Copy(args[0]), func(node ast.Node) ast.Node {
+        if err != nil || !isUnquoteCall(node) {
+            return node
+        }
+
+        call := node.(*ast.CallExpression)
+        if len(call.Arguments) != 1 {
+            err = newKindError(object.ARGUMENT_ERROR, "unquote expects 1 argument, got %d", len(call.Arguments))
+            return node
+        }
+
+        unquoted := Eval(call.Arguments[0], env)
+        if isError(unquoted) {
+            err = unquoted
+            return node
+        }
+
+        converted := convertObjectToASTNode(unquoted)
+        if converted == nil {
+            err = newKindError(object.TYPE_ERROR, "cannot unquote %s", unquoted.Type())
+            return node
+        }
+
+        return converted
+    })
+
+    if err != nil {
+        return err
+    }
+
+    return &object.Quote{Node: node}
+}
+
+// convertObjectToASTNode turns a runtime value back into the literal that
+// would produce it, or returns nil if the value has no literal form.
+func convertObjectToASTNode(obj object.Object) ast.Expression {
+    switch obj := obj.(type) {
+    case *object.Integer:
+        t := token.Token{Type: token.INT, Literal: fmt.Sprintf("%d", obj.Value)}
+        return &ast.IntegerLiteral{Token: t, Value: obj.Value}
+    case *object.Boolean:
+        t := token.Token{Type: token.FALSE, Literal: "false"}
+        if obj.Value {
+            t = token.Token{Type: token.TRUE, Literal: "true"}
+        }
+        return &ast.Boolean{Token: t, Value: obj.Value}
+    case *object.String:
+        t := token.Token{Type: token.STRING, Literal: obj.Value}
+        return &ast.StringLiteral{Token: t, Value: obj.Value}
+    case *object.Array:
+        array := &ast.ArrayLiteral{Token: token.Token{Type: token.LEFTBRACKET, Literal: "["}, Elements: []ast.Expression{}}
+        for _, el := range obj.Elements {
+            node := convertObjectToASTNode(el)
+            if node == nil {
+                return nil
+            }
+            array.Elements = append(array.Elements, node)
+        }
+        return array
+    case *object.Hash:
+        hash := &ast.HashLiteral{Token: token.Token{Type: token.LEFTBRACE, Literal: "{"}}
+        hash.Pairs = make([]ast.HashPair, 0, len(obj.Pairs))
+        for _, pair := range obj.Ordered() {
+            key := convertObjectToASTNode(pair.Key)
+            value := convertObjectToASTNode(pair.Value)
+            if key == nil || value == nil {
+                return nil
+            }
+            hash.Pairs = append(hash.Pairs, ast.HashPair{Key: key, Value: value})
+        }
+        return hash
+    case *object.Quote:
+        exp, _ := obj.Node.(ast.Expression)
+        return exp
+    default:
+        return nil
+    }
+}
@@ -0,0 +1,661 @@
+// Package format turns Elliott syntax trees back into canonical source.
+//
+// Output is indented with four spaces, uses the fewest parentheses that
+// reparse to the same tree and keeps comments. A comment stays with the
+// statement, match arm, hash pair or array element it comes before or ends
+// the line of; a hash or array holding comments is printed one entry per
+// line. Any other comment inside a statement moves to its own line before
+// that statement. Formatting formatted source again leaves it unchanged.
+package format
+
+import (
+    "fmt"
+    "reflect"
+    "strings"
+    "github.com/JakeNorman007/interpreter/ast"
+    "github.com/JakeNorman007/interpreter/lexer"
+    "github.com/JakeNorman007/interpreter/parser"
+    "github.com/JakeNorman007/interpreter/token"
+)
+
+const indentUnit = "    "
+
+//binds tighter than anything the parser knows about
+const primary = parser.INDEX + 1
+
+var operatorPrecedences = map[string]int {
+    "==":   parser.EQUALS,
+    "!=":   parser.EQUALS,
+    "<":    parser.LESSGREATER,
+    ">":    parser.LESSGREATER,
+    "in":   parser.LESSGREATER,
+    "+":    parser.SUM,
+    "-":    parser.SUM,
+    "*":    parser.PRODUCT,
+    "/":    parser.PRODUCT,
+}
+
+// Source formats src. It fails if src does not parse.
+func Source(src []byte) ([]byte, error) {
+    l := lexer.New(string(src))
+    p := parser.New(l)
+
+    program := p.ParseProgram()
+    if len(p.Errors()) != 0 {
+        return nil, fmt.Errorf("parse errors:\n\t%s", strings.Join(p.Errors(), "\n\t"))
+    }
+
+    printer := &printer{comments: l.Comments(), lines: strings.Split(string(src), "\n")}
+
+    out := printer.statementList(program.Statements, 0, false)
+    if out == "" {
+        return []byte{}, nil
+    }
+
+    return []byte(out + "\n"), nil
+}
+
+type printer struct {
+    indent      int
+    comments    []token.Token
+    next        int //index of the first comment not yet printed
+    lines       []string //the source, to find blank lines
+}
+
+//one line-level item of a statement list: a statement or a comment
+type item struct {
+    text        string
+    line        int //source line the item starts on
+    statement   ast.Statement
+    semicolon   bool //an expression statement that ends in } and may need a ;
+    trailing    string //a comment on the statement's last line
+}
+
+// statementList prints statements one per line, along with the comments
+// before endLine, or every remaining comment at the top level. In a block
+// each item starts on a fresh line; at the top level items are only
+// separated. A blank line in the source before an item is kept.
+func (p *printer) statementList(statements []ast.Statement, endLine int, block bool) string {
+    items := []*item{}
+
+    for i, stmt := range statements {
+        start, end := lineSpan(stmt)
+        items = append(items, p.commentsBefore(start)...)
+
+        it := &item{line: start, statement: stmt}
+        it.text, it.semicolon = p.statement(stmt, block && i == len(statements) - 1)
+
+        //comments the statement had nowhere to put, such as between call
+        //arguments, go before it rather than after it
+        items = append(items, p.commentsBefore(end)...)
+
+        if c, ok := p.peekComment(); ok && end != 0 && c.Line == end {
+            it.trailing = c.Literal
+            p.next++
+        }
+
+        items = append(items, it)
+    }
+
+    if block {
+        items = append(items, p.commentsBefore(endLine)...)
+    } else {
+        items = append(items, p.commentsBefore(-1)...)
+    }
+
+    var out strings.Builder
+    indent := strings.Repeat(indentUnit, p.indent)
+
+    for i, it := range items {
+        if it.statement != nil && it.semicolon && continuesExpression(items[i + 1:]) {
+            it.text += ";"
+        }
+
+        if i > 0 || block {
+            out.WriteString("\n")
+            if i > 0 && p.blankBefore(it.line) {
+                out.WriteString("\n")
+            }
+        }
+
+        out.WriteString(indent)
+        out.WriteString(it.text)
+        if it.trailing != "" {
+            out.WriteString(" " + it.trailing)
+        }
+    }
+
+    return out.String()
+}
+
+func (p *printer) blankBefore(line int) bool {
+    return line >= 2 && line - 2 < len(p.lines) && strings.TrimSpace(p.lines[line - 2]) == ""
+}
+
+//returns items for the unprinted comments before line, or all of them when
+//line is negative
+func (p *printer) commentsBefore(line int) []*item {
+    items := []*item{}
+
+    for p.next < len(p.comments) {
+        c := p.comments[p.next]
+        if line >= 0 && (line == 0 || c.Line >= line) {
+            break
+        }
+
+        items = append(items, &item{text: c.Literal, line: c.Line})
+        p.next++
+    }
+
+    return items
+}
+
+func (p *printer) peekComment() (token.Token, bool) {
+    if p.next < len(p.comments) {
+        return p.comments[p.next], true
+    }
+
+    return token.Token{}, false
+}
+
+//reports whether the next statement in items would be parsed as continuing
+//the expression before it if no ; separated them, as in `if (a) { b } [c]`
+func continuesExpression(items []*item) bool {
+    for _, it := range items {
+        if it.statement == nil {
+            continue
+        }
+
+        if strings.HasPrefix(it.text, "in ") {
+            return true
+        }
+        return it.text != "" && strings.ContainsRune("([.-+*/<>=!?|", rune(it.text[0]))
+    }
+
+    return false
+}
+
+// statement returns the source for stmt. Expression statements get a ;
+// unless last is set or they end in a brace, in which case the second result
+// asks the caller to add one only where it is needed.
+func (p *printer) statement(stmt ast.Statement, last bool) (string, bool) {
+    switch stmt := stmt.(type) {
+    case *ast.LetStatement:
+        var target string
+        if stmt.Pattern != nil {
+            target = p.pattern(stmt.Pattern)
+        } else {
+            target = stmt.Name.Value
+        }
+        return stmt.Token.Literal + " " + target + " = " + p.expr(stmt.Value, parser.LOWEST) + ";", false
+    case *ast.ReturnStatement:
+        if stmt.ReturnValue == nil {
+            return "return;", false
+        }
+        return "return " + p.expr(stmt.ReturnValue, parser.LOWEST) + ";", false
+    case *ast.ThrowStatement:
+        return "throw " + p.expr(stmt.Value, parser.LOWEST) + ";", false
+    case *ast.DeferStatement:
+        return "defer " + p.expr(stmt.Call, parser.LOWEST) + ";", false
+    case *ast.ImportStatement:
+        return "import " + quoteString(stmt.Path.Value) + " as " + stmt.Alias.Value + ";", false
+    case *ast.ExportStatement:
+        inner, _ := p.statement(stmt.Statement, false)
+        return "export " + inner, false
+    case *ast.FunctionStatement:
+        fn := stmt.Function
+        return "func " + stmt.Name.Value + "(" + p.parameters(fn.Parameters, fn.Defaults, fn.Rest) + ") " + p.block(fn.Body), false
+    case *ast.ExpressionStatement:
+        text := p.expr(stmt.Expression, parser.LOWEST)
+        if last {
+            return text, false
+        }
+        if strings.HasSuffix(text, "}") {
+            return text, true
+        }
+        return text + ";", false
+    default:
+        return stmt.String(), false
+    }
+}
+
+// block prints a braced block. A block that fit on one line in the source
+// and holds at most one statement stays on one line.
+func (p *printer) block(block *ast.BlockStatement) string {
+    if len(block.Statements) == 0 && !p.hasCommentsBefore(block.End.Line) {
+        return "{}"
+    }
+
+    if block.Token.Line != 0 && block.Token.Line == block.End.Line && len(block.Statements) == 1 {
+        next := p.next
+        text, _ := p.statement(block.Statements[0], true)
+        if !strings.Contains(text, "\n") {
+            return "{ " + text + " }"
+        }
+        p.next = next
+    }
+
+    p.indent++
+    body := p.statementList(block.Statements, block.End.Line, true)
+    p.indent--
+
+    return "{" + body + "\n" + strings.Repeat(indentUnit, p.indent) + "}"
+}
+
+func (p *printer) hasCommentsBefore(line int) bool {
+    c, ok := p.peekComment()
+    return ok && line != 0 && c.Line < line
+}
+
+// expr prints e, parenthesised if it binds looser than minPrecedence.
+func (p *printer) expr(e ast.Expression, minPrecedence int) string {
+    text, precedence := p.expression(e)
+
+    if precedence < minPrecedence {
+        return "(" + text + ")"
+    }
+
+    return text
+}
+
+func (p *printer) expression(e ast.Expression) (string, int) {
+    switch e := e.(type) {
+    case *ast.Identifier:
+        return e.Value, primary
+    case *ast.IntegerLiteral:
+        return fmt.Sprintf("%d", e.Value), primary
+    case *ast.Boolean:
+        return fmt.Sprintf("%t", e.Value), primary
+    case *ast.StringLiteral:
+        return quoteString(e.Value), primary
+    case *ast.PrefixExpression:
+        return e.Operator + p.expr(e.Right, parser.PREFIX), parser.PREFIX
+    case *ast.InfixExpression:
+        precedence := operatorPrecedences[e.Operator]
+        return p.binary(e.Left, e.Operator, e.Right, precedence), precedence
+    case *ast.PipelineExpression:
+        return p.binary(e.Left, "|>", e.Right, parser.PIPE), parser.PIPE
+    case *ast.CoalesceExpression:
+        return p.binary(e.Left, "??", e.Right, parser.COALESCE), parser.COALESCE
+    case *ast.RangeExpression:
+        op := "..<"
+        if e.Inclusive {
+            op = ".."
+        }
+        return p.expr(e.Start, parser.RANGE) + op + p.expr(e.End, parser.RANGE + 1), parser.RANGE
+    case *ast.CallExpression:
+        return p.expr(e.Function, parser.CALL) + "(" + p.list(e.Arguments) + ")", parser.CALL
+    case *ast.IndexExpression:
+        return p.expr(e.Left, parser.CALL) + "[" + p.expr(e.Index, parser.LOWEST) + "]", parser.CALL
+    case *ast.OptionalIndexExpression:
+        return p.expr(e.Left, parser.CALL) + "?.[" + p.expr(e.Index, parser.LOWEST) + "]", parser.CALL
+    case *ast.SliceExpression:
+        open := "["
+        if e.Optional {
+            open = "?.["
+        }
+        return p.expr(e.Left, parser.CALL) + open + p.slice(e) + "]", parser.CALL
+    case *ast.PropertyExpression:
+        return p.expr(e.Object, parser.CALL) + "." + e.Property.Value, parser.CALL
+    case *ast.OptionalPropertyExpression:
+        return p.expr(e.Object, parser.CALL) + "?." + e.Property.Value, parser.CALL
+    case *ast.SpreadExpression:
+        return "..." + p.expr(e.Value, parser.LOWEST), parser.LOWEST
+    case *ast.KeywordArgument:
+        return e.Name.Value + ": " + p.expr(e.Value, parser.LOWEST), parser.LOWEST
+    case *ast.ArrayLiteral:
+        if p.hasCommentsBefore(e.End.Line) {
+            return "[" + p.entries(p.arrayEntries(e), e.End.Line) + "]", primary
+        }
+        return "[" + p.list(e.Elements) + "]", primary
+    case *ast.HashLiteral:
+        if p.hasCommentsBefore(e.End.Line) {
+            return "{" + p.entries(p.hashEntries(e), e.End.Line) + "}", primary
+        }
+        pairs := []string{}
+        for _, pair := range e.Pairs {
+            pairs = append(pairs, p.hashPair(pair))
+        }
+        return "{" + strings.Join(pairs, ", ") + "}", primary
+    case *ast.ListComprehension:
+        return "[" + p.expr(e.Element, parser.LOWEST) + p.comprehension(e.Variables, e.Iterable, e.Condition) + "]", primary
+    case *ast.HashComprehension:
+        pair := p.expr(e.Key, parser.LOWEST) + ": " + p.expr(e.Value, parser.LOWEST)
+        return "{" + pair + p.comprehension(e.Variables, e.Iterable, e.Condition) + "}", primary
+    case *ast.IfExpression:
+        out := "if (" + p.expr(e.Condition, parser.LOWEST) + ") " + p.block(e.Consequence)
+        if e.Alternative != nil {
+            out += " else " + p.block(e.Alternative)
+        }
+        return out, primary
+    case *ast.TryExpression:
+        out := "try " + p.block(e.Block)
+        if e.Catch != nil {
+            out += " catch "
+            if e.Param != nil {
+                out += "(" + e.Param.Value + ") "
+            }
+            out += p.block(e.Catch)
+        }
+        if e.Finally != nil {
+            out += " finally " + p.block(e.Finally)
+        }
+        return out, primary
+    case *ast.FunctionLiteral:
+        if e.Token.Type == token.ARROW {
+            return p.arrow(e), parser.LOWEST
+        }
+        return "func(" + p.parameters(e.Parameters, e.Defaults, e.Rest) + ") " + p.block(e.Body), primary
+    case *ast.MacroLiteral:
+        return "macro(" + p.parameters(e.Parameters, nil, nil) + ") " + p.block(e.Body), primary
+    case *ast.MatchExpression:
+        return p.match(e), primary
+    default:
+        return e.String(), primary
+    }
+}
+
+func (p *printer) binary(left ast.Expression, op string, right ast.Expression, precedence int) string {
+    return p.expr(left, precedence) + " " + op + " " + p.expr(right, precedence + 1)
+}
+
+func (p *printer) hashPair(pair ast.HashPair) string {
+    return p.expr(pair.Key, parser.LOWEST) + ": " + p.expr(pair.Value, parser.LOWEST)
+}
+
+//one part of a bracketed list, with the source lines it covers
+type entry struct {
+    start       int
+    end         int
+    print       func() string
+}
+
+func (p *printer) arrayEntries(e *ast.ArrayLiteral) []entry {
+    list := []entry{}
+    for _, el := range e.Elements {
+        el := el
+        start, end := lineSpan(el)
+        list = append(list, entry{start, end, func() string { return p.expr(el, parser.LOWEST) }})
+    }
+
+    return list
+}
+
+func (p *printer) hashEntries(e *ast.HashLiteral) []entry {
+    list := []entry{}
+    for _, pair := range e.Pairs {
+        pair := pair
+        start, _ := lineSpan(pair.Key)
+        _, end := lineSpan(pair.Value)
+        list = append(list, entry{start, end, func() string { return p.hashPair(pair) }})
+    }
+
+    return list
+}
+
+// entries prints the inside of a bracket closed on line end, one entry per
+// line. Each entry is preceded by the comments before it and followed by a
+// comment ending its last line; comments before the closing bracket stay
+// inside it. Entries are printed in order, as they consume comments.
+func (p *printer) entries(list []entry, end int) string {
+    var out strings.Builder
+
+    p.indent++
+    indent := strings.Repeat(indentUnit, p.indent)
+    for i, e := range list {
+        for _, c := range p.commentsBefore(e.start) {
+            out.WriteString("\n" + indent + c.text)
+        }
+
+        out.WriteString("\n" + indent + e.print())
+        if i < len(list) - 1 {
+            out.WriteString(",")
+        }
+
+        //a comment after two entries sharing a line belongs to the second
+        sharesLine := i < len(list) - 1 && list[i + 1].start == e.end
+        if c, ok := p.peekComment(); ok && e.end != 0 && c.Line == e.end && !sharesLine {
+            out.WriteString(" " + c.Literal)
+            p.next++
+        }
+    }
+    for _, c := range p.commentsBefore(end) {
+        out.WriteString("\n" + indent + c.text)
+    }
+    p.indent--
+
+    out.WriteString("\n" + strings.Repeat(indentUnit, p.indent))
+
+    return out.String()
+}
+
+func (p *printer) list(exps []ast.Expression) string {
+    parts := []string{}
+    for _, exp := range exps {
+        parts = append(parts, p.expr(exp, parser.LOWEST))
+    }
+
+    return strings.Join(parts, ", ")
+}
+
+func (p *printer) slice(e *ast.SliceExpression) string {
+    var out strings.Builder
+
+    if e.Start != nil {
+        out.WriteString(p.expr(e.Start, parser.LOWEST))
+    }
+    out.WriteString(":")
+    if e.End != nil {
+        out.WriteString(p.expr(e.End, parser.LOWEST))
+    }
+    if e.Step != nil {
+        out.WriteString(":")
+        out.WriteString(p.expr(e.Step, parser.LOWEST))
+    }
+
+    return out.String()
+}
+
+func (p *printer) comprehension(variables []*ast.Identifier, iterable, condition ast.Expression) string {
+    names := []string{}
+    for _, v := range variables {
+        names = append(names, v.Value)
+    }
+
+    out := " for " + strings.Join(names, ", ") + " in " + p.expr(iterable, parser.LOWEST)
+    if condition != nil {
+        out += " if " + p.expr(condition, parser.LOWEST)
+    }
+
+    return out
+}
+
+func (p *printer) parameters(params []*ast.Identifier, defaults []ast.Expression, rest *ast.Identifier) string {
+    parts := []string{}
+
+    for i, param := range params {
+        if i < len(defaults) && defaults[i] != nil {
+            parts = append(parts, param.Value + " = " + p.expr(defaults[i], parser.LOWEST))
+        } else {
+            parts = append(parts, param.Value)
+        }
+    }
+
+    if rest != nil {
+        parts = append(parts, "..." + rest.Value)
+    }
+
+    return strings.Join(parts, ", ")
+}
+
+//prints x => body for a lone plain parameter, (params) => body otherwise; an
+//expression body is printed bare unless it would read as a block
+func (p *printer) arrow(fn *ast.FunctionLiteral) string {
+    params := "(" + p.parameters(fn.Parameters, fn.Defaults, fn.Rest) + ")"
+    if len(fn.Parameters) == 1 && fn.Rest == nil && !hasDefault(fn.Defaults) {
+        params = fn.Parameters[0].Value
+    }
+
+    if fn.Body.Token.Type == token.LEFTBRACE || len(fn.Body.Statements) != 1 {
+        return params + " => " + p.block(fn.Body)
+    }
+
+    stmt, ok := fn.Body.Statements[0].(*ast.ExpressionStatement)
+    if !ok {
+        return params + " => " + p.block(fn.Body)
+    }
+
+    body := p.expr(stmt.Expression, parser.LOWEST)
+    if strings.HasPrefix(body, "{") {
+        body = "(" + body + ")"
+    }
+
+    return params + " => " + body
+}
+
+func (p *printer) match(e *ast.MatchExpression) string {
+    subject := p.expr(e.Subject, parser.LOWEST)
+
+    arms := []entry{}
+    for _, arm := range e.Arms {
+        arm := arm
+        start, end := lineSpan(arm)
+        arms = append(arms, entry{start, end, func() string { return p.arm(arm) }})
+    }
+
+    return "match (" + subject + ") {" + p.entries(arms, e.End.Line) + "}"
+}
+
+func (p *printer) arm(arm *ast.MatchArm) string {
+    out := p.pattern(arm.Pattern)
+    if arm.Guard != nil {
+        out += " if " + p.expr(arm.Guard, parser.LOWEST)
+    }
+
+    return out + " => " + p.expr(arm.Body, parser.LOWEST)
+}
+
+func (p *printer) pattern(pattern ast.Expression) string {
+    switch pattern := pattern.(type) {
+    case *ast.Identifier:
+        return pattern.Value
+    case *ast.DefaultPattern:
+        return p.pattern(pattern.Pattern) + " = " + p.expr(pattern.Default, parser.LOWEST)
+    case *ast.ArrayPattern:
+        parts := []string{}
+        for _, el := range pattern.Elements {
+            parts = append(parts, p.pattern(el))
+        }
+        if pattern.Rest != nil {
+            parts = append(parts, "..." + pattern.Rest.Value)
+        }
+        return "[" + strings.Join(parts, ", ") + "]"
+    case *ast.HashPattern:
+        parts := []string{}
+        for i, key := range pattern.Keys {
+            parts = append(parts, p.hashPatternEntry(key, pattern.Values[i]))
+        }
+        return "{" + strings.Join(parts, ", ") + "}"
+    default:
+        return p.expr(pattern, parser.PREFIX)
+    }
+}
+
+func (p *printer) hashPatternEntry(key string, value ast.Expression) string {
+    binding := value
+    if dp, ok := value.(*ast.DefaultPattern); ok {
+        binding = dp.Pattern
+    }
+
+    if ident, ok := binding.(*ast.Identifier); ok && ident.Value == key && isIdentifier(key) {
+        return p.pattern(value)
+    }
+
+    if isIdentifier(key) {
+        return key + ": " + p.pattern(value)
+    }
+
+    return quoteString(key) + ": " + p.pattern(value)
+}
+
+func isIdentifier(s string) bool {
+    if s == "" || token.LookupIdent(s) != token.IDENT {
+        return false
+    }
+
+    for i := 0; i < len(s); i++ {
+        ch := s[i]
+        if !('a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_') {
+            return false
+        }
+    }
+
+    return true
+}
+
+func hasDefault(defaults []ast.Expression) bool {
+    for _, d := range defaults {
+        if d != nil {
+            return true
+        }
+    }
+
+    return false
+}
+
+//strings have no escapes, so the value is printed as is
+func quoteString(s string) string {
+    return "\"" + s + "\""
+}
+
+// lineSpan returns the first and last source lines node covers, or zeros if
+// it carries no positions.
+func lineSpan(node ast.Node) (int, int) {
+    start, end := 0, 0
+
+    ast.Inspect(node, func(n ast.Node) bool {
+        if n == nil {
+            return false
+        }
+
+        lines := []int{tokenOf(n).Line, tokenField(n, "End").Line}
+
+        for _, line := range lines {
+            if line == 0 {
+                continue
+            }
+            if start == 0 || line < start {
+                start = line
+            }
+            if line > end {
+                end = line
+            }
+        }
+
+        return true
+    })
+
+    return start, end
+}
+
+//every node keeps the token it was parsed from in a Token field
+func tokenOf(node ast.Node) token.Token {
+    return tokenField(node, "Token")
+}
+
+//returns the token in node's field called name, if it has one; nodes
+//closed by a bracket keep that bracket in End
+func tokenField(node ast.Node, name string) token.Token {
+    v := reflect.ValueOf(node)
+    if v.Kind() != reflect.Ptr || v.IsNil() {
+        return token.Token{}
+    }
+
+    field := v.Elem().FieldByName(name)
+    if !field.IsValid() {
+        return token.Token{}
+    }
+
+    tok, _ := field.Interface().(token.Token)
+    return tok
+}
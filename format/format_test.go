@@ -0,0 +1,199 @@
+package format
+
+import (
+    "os"
+    "testing"
+    "github.com/JakeNorman007/interpreter/lexer"
+    "github.com/JakeNorman007/interpreter/parser"
+)
+
+func TestFormat(t *testing.T) {
+    tests := []struct {
+        input       string
+        expected    string
+    }{
+        {"let x   =   1 + 2 * 3", "let x = 1 + 2 * 3;\n"},
+        {"(1 + 2) * 3; 1 + (2 * 3); (1 - 2) - 3; 1 - (2 - 3)", "(1 + 2) * 3;\n1 + 2 * 3;\n1 - 2 - 3;\n1 - (2 - 3);\n"},
+        {"-(a + b); -a * b; !(a == b); (-a)(1); -a(1)", "-(a + b);\n-a * b;\n!(a == b);\n(-a)(1);\n-a(1);\n"},
+        {"xs |> map(f) |> len; a ?? (b ?? c); 1..n + 1; (1..n)[0]", "xs |> map(f) |> len;\na ?? (b ?? c);\n1..n + 1;\n(1..n)[0];\n"},
+        {"x in 1..<10; a.b?.c?.[0]; xs[1:]; xs[::2]", "x in 1..<10;\na.b?.c?.[0];\nxs[1:];\nxs[::2];\n"},
+        {"xs?.[1:]", "xs?.[1:];\n"},
+        {"let f = func(a, b = 1, ...rest) { a }", "let f = func(a, b = 1, ...rest) { a };\n"},
+        {"let f = func(a) { let b = a; b }", "let f = func(a) {\n    let b = a;\n    b\n};\n"},
+        {"func double(x) {\nx * 2\n}", "func double(x) {\n    x * 2\n}\n"},
+        {"let g = x => x + 1; let h = (a, b) => a; let k = () => ({\"a\": 1})", "let g = x => x + 1;\nlet h = (a, b) => a;\nlet k = () => ({\"a\": 1});\n"},
+        {"apply((x => x), 1); (x => x) |> f", "apply(x => x, 1);\n(x => x) |> f;\n"},
+        {"if (a) { b } else { c }", "if (a) { b } else { c }\n"},
+        {"if (a) { b }; [1]", "if (a) { b };\n[1];\n"},
+        {"if (a) { b }\nlet c = 1", "if (a) { b }\nlet c = 1;\n"},
+        {"let f = func() {}", "let f = func() {};\n"},
+        {"let h = {\"b\": 1, \"a\": 2, c: 3}", "let h = {\"b\": 1, \"a\": 2, c: 3};\n"},
+        {"match (x) { 1 => a, [h, ...t] if h > 0 => h, {\"first name\": n, age = 1} => n, _ => 0 }",
+            "match (x) {\n    1 => a,\n    [h, ...t] if h > 0 => h,\n    {\"first name\": n, age = 1} => n,\n    _ => 0\n}\n"},
+        {"let [a, b = 2, ...rest] = xs; const {name, age: years} = person", "let [a, b = 2, ...rest] = xs;\nconst {name, age: years} = person;\n"},
+        {"try { f() } catch (e) { e } finally { g() }; try { f() } catch { 0 }", "try { f() } catch (e) { e } finally { g() }\ntry { f() } catch { 0 }\n"},
+        {"[x * 2 for x in xs if x > 0]; {k: v for k, v in h}", "[x * 2 for x in xs if x > 0];\n{k: v for k, v in h}\n"},
+        {"f(...xs, key: 1); defer close(f); throw \"boom\"", "f(...xs, key: 1);\ndefer close(f);\nthrow \"boom\";\n"},
+        {"import \"lib/math\" as m; export func sq(x) { x * x }", "import \"lib/math\" as m;\nexport func sq(x) { x * x }\n"},
+        {"let m = macro(a, b) { quote(unquote(a) + unquote(b)) }", "let m = macro(a, b) { quote(unquote(a) + unquote(b)) };\n"},
+        {"", ""},
+    }
+
+    for _, tt := range tests {
+        out, err := Source([]byte(tt.input))
+        if err != nil {
+            t.Fatalf("Source(%q) failed: %s", tt.input, err)
+        }
+
+        if string(out) != tt.expected {
+            t.Errorf("wrong output for %q.\nexpected=%q\ngot=     %q", tt.input, tt.expected, string(out))
+        }
+
+        checkStableAndEquivalent(t, tt.input, out)
+    }
+}
+
+func TestFormatComments(t *testing.T) {
+    input := `// leading
+let a = 1;   // trailing
+
+
+// before b
+let b = func(x) {
+    // inside
+    x
+
+    // before end
+};
+let c = [1, // dropped into the next line
+    2];
+let d = 4;
+// end of file`
+
+    expected := `// leading
+let a = 1; // trailing
+
+// before b
+let b = func(x) {
+    // inside
+    x
+
+    // before end
+};
+let c = [
+    1, // dropped into the next line
+    2
+];
+let d = 4;
+// end of file
+`
+
+    out, err := Source([]byte(input))
+    if err != nil {
+        t.Fatalf("Source failed: %s", err)
+    }
+
+    if string(out) != expected {
+        t.Errorf("wrong output.\nexpected=%q\ngot=     %q", expected, string(out))
+    }
+
+    checkStableAndEquivalent(t, input, out)
+}
+
+func TestFormatNestedComments(t *testing.T) {
+    input := `let r = match (x) {
+    // zero first
+    0 => "zero", // exact
+    // then small numbers
+    n if n < 10 => "small",
+    _ => "big"
+    // no more arms
+};
+let h = {
+    // the name
+    "name": "elliott",
+    "age": 3, // years
+    "tags": [1, 2]
+};
+let e = {
+    // nothing yet
+};
+f(1,
+    // second argument
+    2);`
+
+    expected := `let r = match (x) {
+    // zero first
+    0 => "zero", // exact
+    // then small numbers
+    n if n < 10 => "small",
+    _ => "big"
+    // no more arms
+};
+let h = {
+    // the name
+    "name": "elliott",
+    "age": 3, // years
+    "tags": [1, 2]
+};
+let e = {
+    // nothing yet
+};
+// second argument
+f(1, 2);
+`
+
+    out, err := Source([]byte(input))
+    if err != nil {
+        t.Fatalf("Source failed: %s", err)
+    }
+
+    if string(out) != expected {
+        t.Errorf("wrong output.\nexpected=%q\ngot=     %q", expected, string(out))
+    }
+
+    checkStableAndEquivalent(t, input, out)
+}
+
+func TestFormatPrelude(t *testing.T) {
+    src, err := os.ReadFile("../prelude/prelude.ell")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    out, err := Source(src)
+    if err != nil {
+        t.Fatalf("Source failed: %s", err)
+    }
+
+    checkStableAndEquivalent(t, string(src), out)
+}
+
+func TestFormatParseError(t *testing.T) {
+    if _, err := Source([]byte("let = 1;")); err == nil {
+        t.Errorf("expected an error for source that does not parse")
+    }
+}
+
+// checkStableAndEquivalent checks that formatted reparses to the same tree as
+// input and that formatting it again changes nothing.
+func checkStableAndEquivalent(t *testing.T, input string, formatted []byte) {
+    t.Helper()
+
+    again, err := Source(formatted)
+    if err != nil {
+        t.Fatalf("formatted output does not parse: %s\n%s", err, formatted)
+    }
+
+    if string(again) != string(formatted) {
+        t.Errorf("formatting is not stable.\nfirst= %q\nsecond=%q", formatted, again)
+    }
+
+    if parse(input) != parse(string(formatted)) {
+        t.Errorf("formatting changed the program.\nbefore=%q\nafter= %q", parse(input), parse(string(formatted)))
+    }
+}
+
+func parse(input string) string {
+    return parser.New(lexer.New(input)).ParseProgram().String()
+}
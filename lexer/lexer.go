@@ -1,21 +1,48 @@
 package lexer
 
-import "github.com/JakeNorman007/interpreter/token"
+import (
+    "strings"
+    "github.com/JakeNorman007/interpreter/token"
+)
 
 type Lexer struct {
     input           string
     position        int
     readPosition    int
     ch              byte
+    line            int //line of ch
+    column          int //column of ch
+    comments        []token.Token
 }
 
 func New(input string) *Lexer {
-    l := &Lexer{input: input}
+    l := &Lexer{input: input, line: 1}
     l.readChar()
     return l
 }
 
+// Comments returns the comments skipped so far, in source order.
+func (l *Lexer) Comments() []token.Token {
+    return l.comments
+}
+
+// Copy returns a lexer that carries on from where l is, for looking ahead
+// without consuming l's tokens. Comments the copy reads are not added to l's.
+func (l *Lexer) Copy() *Lexer {
+    c := *l
+    //capping the capacity makes the copy's appends reallocate rather than
+    //write into l's backing array
+    c.comments = l.comments[:len(l.comments):len(l.comments)]
+    return &c
+}
+
 func (l *Lexer) readChar() {
+    if l.ch == '\n' {
+        l.line++
+        l.column = 0
+    }
+    l.column++
+
     if l.readPosition >= len(l.input) {
         l.ch = 0
     }else {
@@ -27,16 +54,33 @@ func (l *Lexer) readChar() {
 }
 
 func (l *Lexer) NextToken() token.Token {
-    var tok token.Token
-
     l.eatWhitespace()
 
+    for l.ch == '/' && l.peepChar() == '/' {
+        l.readComment()
+        l.eatWhitespace()
+    }
+
+    line, column := l.line, l.column
+    tok := l.readToken()
+    tok.Line, tok.Column = line, column
+
+    return tok
+}
+
+func (l *Lexer) readToken() token.Token {
+    var tok token.Token
+
     switch l.ch {
     case '=':
         if l.peepChar() == '=' {
             ch := l.ch
             l.readChar()
             tok = token.Token{Type: token.EQUAL, Literal: string(ch) + string(l.ch)}
+        } else if l.peepChar() == '>' {
+            ch := l.ch
+            l.readChar()
+            tok = token.Token{Type: token.ARROW, Literal: string(ch) + string(l.ch)}
         } else {
             tok = newToken(token.ASSIGN, l.ch)
         }
@@ -48,6 +92,26 @@ func (l *Lexer) NextToken() token.Token {
         } else {
             tok = newToken(token.BANG, l.ch)
         }
+    case '?':
+        if l.peepChar() == '?' {
+            ch := l.ch
+            l.readChar()
+            tok = token.Token{Type: token.COALESCE, Literal: string(ch) + string(l.ch)}
+        } else if l.peepChar() == '.' {
+            ch := l.ch
+            l.readChar()
+            tok = token.Token{Type: token.OPTIONAL_CHAIN, Literal: string(ch) + string(l.ch)}
+        } else {
+            tok = newToken(token.ILLEGAL, l.ch)
+        }
+    case '|':
+        if l.peepChar() == '>' {
+            ch := l.ch
+            l.readChar()
+            tok = token.Token{Type: token.PIPE, Literal: string(ch) + string(l.ch)}
+        } else {
+            tok = newToken(token.ILLEGAL, l.ch)
+        }
     case ';':
         tok = newToken(token.SEMICOLON, l.ch)
     case '(':
@@ -81,6 +145,21 @@ func (l *Lexer) NextToken() token.Token {
         tok = newToken(token.RIGHTBRACKET, l.ch)
     case ':':
         tok = newToken(token.COLON, l.ch)
+    case '.':
+        if l.peepChar() == '.' && l.peepCharAt(1) == '.' {
+            l.readChar()
+            l.readChar()
+            tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+        } else if l.peepChar() == '.' && l.peepCharAt(1) == '<' {
+            l.readChar()
+            l.readChar()
+            tok = token.Token{Type: token.RANGE_EXCLUSIVE, Literal: "..<"}
+        } else if l.peepChar() == '.' {
+            l.readChar()
+            tok = token.Token{Type: token.RANGE, Literal: ".."}
+        } else {
+            tok = newToken(token.DOT, l.ch)
+        }
     case 0:
         tok.Literal = ""
         tok.Type = token.EOF
@@ -102,6 +181,18 @@ func (l *Lexer) NextToken() token.Token {
     return tok
 }
 
+func (l *Lexer) readComment() {
+    tok := token.Token{Type: token.COMMENT, Line: l.line, Column: l.column}
+    position := l.position
+
+    for l.ch != '\n' && l.ch != 0 {
+        l.readChar()
+    }
+
+    tok.Literal = strings.TrimRight(l.input[position:l.position], " \t\r")
+    l.comments = append(l.comments, tok)
+}
+
 func (l *Lexer) readNumber() string {
     position := l.position
     for isDigit(l.ch) {
@@ -159,3 +250,11 @@ func (l *Lexer) peepChar() byte {
         return l.input[l.readPosition]
     }
 }
+
+func (l *Lexer) peepCharAt(offset int) byte {
+    if l.readPosition + offset >= len(l.input) {
+        return 0
+    }
+
+    return l.input[l.readPosition + offset]
+}
@@ -137,3 +137,347 @@ func TestNextToken(t *testing.T){
         }
     }
 }
+
+func TestNextTokenEllipsis(t *testing.T) {
+    input := `func(first, ...rest) {}; f(...xs);`
+
+    tests := []struct{
+        expectedType        token.TokenType
+        expectedLiteral     string
+    }{
+        {token.FUNCTION, "func"},
+        {token.LEFTPAREN, "("},
+        {token.IDENT, "first"},
+        {token.COMMA, ","},
+        {token.ELLIPSIS, "..."},
+        {token.IDENT, "rest"},
+        {token.RIGHTPAREN, ")"},
+        {token.LEFTBRACE, "{"},
+        {token.RIGHTBRACE, "}"},
+        {token.SEMICOLON, ";"},
+        {token.IDENT, "f"},
+        {token.LEFTPAREN, "("},
+        {token.ELLIPSIS, "..."},
+        {token.IDENT, "xs"},
+        {token.RIGHTPAREN, ")"},
+        {token.SEMICOLON, ";"},
+        {token.EOF, ""},
+    }
+
+    l := New(input)
+
+    for i, tt := range tests{
+        tok := l.NextToken()
+
+        if tok.Type != tt.expectedType {
+            t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+            i, tt.expectedType, tok.Type)
+        }
+
+        if tok.Literal != tt.expectedLiteral {
+            t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+            i, tt.expectedLiteral, tok.Literal)
+        }
+    }
+}
+
+func TestNextTokenDot(t *testing.T) {
+    input := `person.name; person.greet(1);`
+
+    tests := []struct{
+        expectedType        token.TokenType
+        expectedLiteral     string
+    }{
+        {token.IDENT, "person"},
+        {token.DOT, "."},
+        {token.IDENT, "name"},
+        {token.SEMICOLON, ";"},
+        {token.IDENT, "person"},
+        {token.DOT, "."},
+        {token.IDENT, "greet"},
+        {token.LEFTPAREN, "("},
+        {token.INT, "1"},
+        {token.RIGHTPAREN, ")"},
+        {token.SEMICOLON, ";"},
+        {token.EOF, ""},
+    }
+
+    l := New(input)
+
+    for i, tt := range tests{
+        tok := l.NextToken()
+
+        if tok.Type != tt.expectedType {
+            t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+            i, tt.expectedType, tok.Type)
+        }
+
+        if tok.Literal != tt.expectedLiteral {
+            t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+            i, tt.expectedLiteral, tok.Literal)
+        }
+    }
+}
+
+func TestNextTokenMatch(t *testing.T) {
+    input := `match (x) { 1 => a, _ => b }`
+
+    tests := []struct{
+        expectedType        token.TokenType
+        expectedLiteral     string
+    }{
+        {token.MATCH, "match"},
+        {token.LEFTPAREN, "("},
+        {token.IDENT, "x"},
+        {token.RIGHTPAREN, ")"},
+        {token.LEFTBRACE, "{"},
+        {token.INT, "1"},
+        {token.ARROW, "=>"},
+        {token.IDENT, "a"},
+        {token.COMMA, ","},
+        {token.IDENT, "_"},
+        {token.ARROW, "=>"},
+        {token.IDENT, "b"},
+        {token.RIGHTBRACE, "}"},
+        {token.EOF, ""},
+    }
+
+    l := New(input)
+
+    for i, tt := range tests{
+        tok := l.NextToken()
+
+        if tok.Type != tt.expectedType {
+            t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+            i, tt.expectedType, tok.Type)
+        }
+
+        if tok.Literal != tt.expectedLiteral {
+            t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+            i, tt.expectedLiteral, tok.Literal)
+        }
+    }
+}
+
+func TestNextTokenOptionalOperators(t *testing.T) {
+    input := `a ?? b; a?.b; a?.[0];`
+
+    tests := []struct{
+        expectedType        token.TokenType
+        expectedLiteral     string
+    }{
+        {token.IDENT, "a"},
+        {token.COALESCE, "??"},
+        {token.IDENT, "b"},
+        {token.SEMICOLON, ";"},
+        {token.IDENT, "a"},
+        {token.OPTIONAL_CHAIN, "?."},
+        {token.IDENT, "b"},
+        {token.SEMICOLON, ";"},
+        {token.IDENT, "a"},
+        {token.OPTIONAL_CHAIN, "?."},
+        {token.LEFTBRACKET, "["},
+        {token.INT, "0"},
+        {token.RIGHTBRACKET, "]"},
+        {token.SEMICOLON, ";"},
+        {token.EOF, ""},
+    }
+
+    l := New(input)
+
+    for i, tt := range tests{
+        tok := l.NextToken()
+
+        if tok.Type != tt.expectedType {
+            t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+            i, tt.expectedType, tok.Type)
+        }
+
+        if tok.Literal != tt.expectedLiteral {
+            t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+            i, tt.expectedLiteral, tok.Literal)
+        }
+    }
+}
+
+func TestNextTokenPipe(t *testing.T) {
+    input := `xs |> f(1) | y`
+
+    tests := []struct{
+        expectedType        token.TokenType
+        expectedLiteral     string
+    }{
+        {token.IDENT, "xs"},
+        {token.PIPE, "|>"},
+        {token.IDENT, "f"},
+        {token.LEFTPAREN, "("},
+        {token.INT, "1"},
+        {token.RIGHTPAREN, ")"},
+        {token.ILLEGAL, "|"},
+        {token.IDENT, "y"},
+        {token.EOF, ""},
+    }
+
+    l := New(input)
+
+    for i, tt := range tests{
+        tok := l.NextToken()
+
+        if tok.Type != tt.expectedType {
+            t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+            i, tt.expectedType, tok.Type)
+        }
+
+        if tok.Literal != tt.expectedLiteral {
+            t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+            i, tt.expectedLiteral, tok.Literal)
+        }
+    }
+}
+
+func TestNextTokenRanges(t *testing.T) {
+    input := `1..10; 0..<n; x in xs; ...rest`
+
+    tests := []struct{
+        expectedType        token.TokenType
+        expectedLiteral     string
+    }{
+        {token.INT, "1"},
+        {token.RANGE, ".."},
+        {token.INT, "10"},
+        {token.SEMICOLON, ";"},
+        {token.INT, "0"},
+        {token.RANGE_EXCLUSIVE, "..<"},
+        {token.IDENT, "n"},
+        {token.SEMICOLON, ";"},
+        {token.IDENT, "x"},
+        {token.IN, "in"},
+        {token.IDENT, "xs"},
+        {token.SEMICOLON, ";"},
+        {token.ELLIPSIS, "..."},
+        {token.IDENT, "rest"},
+        {token.EOF, ""},
+    }
+
+    l := New(input)
+
+    for i, tt := range tests{
+        tok := l.NextToken()
+
+        if tok.Type != tt.expectedType {
+            t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+            i, tt.expectedType, tok.Type)
+        }
+
+        if tok.Literal != tt.expectedLiteral {
+            t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+            i, tt.expectedLiteral, tok.Literal)
+        }
+    }
+}
+
+func TestComments(t *testing.T) {
+    input := `// first
+let x = 5; // five
+x / 2 //no space
+// last`
+
+    tests := []struct{
+        expectedType        token.TokenType
+        expectedLiteral     string
+    }{
+        {token.LET, "let"},
+        {token.IDENT, "x"},
+        {token.ASSIGN, "="},
+        {token.INT, "5"},
+        {token.SEMICOLON, ";"},
+        {token.IDENT, "x"},
+        {token.SLASH, "/"},
+        {token.INT, "2"},
+        {token.EOF, ""},
+    }
+
+    l := New(input)
+
+    for i, tt := range tests {
+        tok := l.NextToken()
+
+        if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+            t.Fatalf("tests[%d] - wrong token. expected=%s %q, got=%s %q",
+            i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+        }
+    }
+
+    expected := []token.Token{
+        {Type: token.COMMENT, Literal: "// first", Line: 1, Column: 1},
+        {Type: token.COMMENT, Literal: "// five", Line: 2, Column: 12},
+        {Type: token.COMMENT, Literal: "//no space", Line: 3, Column: 7},
+        {Type: token.COMMENT, Literal: "// last", Line: 4, Column: 1},
+    }
+
+    comments := l.Comments()
+    if len(comments) != len(expected) {
+        t.Fatalf("wrong number of comments. expected=%d, got=%d", len(expected), len(comments))
+    }
+
+    for i, c := range comments {
+        if c != expected[i] {
+            t.Errorf("comments[%d] wrong. expected=%+v, got=%+v", i, expected[i], c)
+        }
+    }
+}
+
+func TestCopy(t *testing.T) {
+    l := New("// one\n// two\n// three\na // four\nb")
+    l.NextToken()
+
+    c := l.Copy()
+    if tok := c.NextToken(); tok.Literal != "b" {
+        t.Fatalf("copy read the wrong token, got=%q", tok.Literal)
+    }
+    if len(c.Comments()) != 4 {
+        t.Errorf("copy should have read 4 comments, got=%d", len(c.Comments()))
+    }
+    if len(l.Comments()) != 3 {
+        t.Errorf("reading the copy changed the original's comments, got=%d", len(l.Comments()))
+    }
+
+    if tok := l.NextToken(); tok.Literal != "b" {
+        t.Fatalf("original read the wrong token, got=%q", tok.Literal)
+    }
+    if got := l.Comments()[3].Literal; got != "// four" {
+        t.Errorf("wrong comment, got=%q", got)
+    }
+}
+
+func TestTokenPositions(t *testing.T) {
+    input := "let x = 5;\n  x + \"a\nb\"\n\tfoo"
+
+    tests := []struct {
+        literal     string
+        line        int
+        column      int
+    }{
+        {"let", 1, 1},
+        {"x", 1, 5},
+        {"=", 1, 7},
+        {"5", 1, 9},
+        {";", 1, 10},
+        {"x", 2, 3},
+        {"+", 2, 5},
+        {"a\nb", 2, 7},
+        {"foo", 4, 2},
+        {"", 4, 5},
+    }
+
+    l := New(input)
+
+    for i, tt := range tests {
+        tok := l.NextToken()
+
+        if tok.Literal != tt.literal || tok.Line != tt.line || tok.Column != tt.column {
+            t.Errorf("tests[%d] - expected %q at %d:%d, got %q at %d:%d",
+            i, tt.literal, tt.line, tt.column, tok.Literal, tok.Line, tok.Column)
+        }
+    }
+}
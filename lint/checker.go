@@ -0,0 +1,314 @@
+package lint
+
+import (
+    "fmt"
+    "strings"
+    "github.com/JakeNorman007/interpreter/ast"
+    "github.com/JakeNorman007/interpreter/evaluator"
+    "github.com/JakeNorman007/interpreter/resolver"
+)
+
+type bindingKind int
+
+const (
+    variable bindingKind = iota //let and const bindings
+    parameter
+)
+
+type checker struct {
+    resolution  *resolver.Resolution
+    kinds       map[*ast.Identifier]bindingKind //the declarations checked for use
+    piped       map[*ast.CallExpression]bool //calls on the right of a |>, which get an extra argument
+    findings    []Finding
+}
+
+func (c *checker) report(node ast.Node, check, format string, args ...interface{}) {
+    line, column := position(node)
+    c.findings = append(c.findings, Finding{Check: check, Message: fmt.Sprintf(format, args...), Line: line, Column: column})
+}
+
+func position(node ast.Node) (int, int) {
+    switch node := node.(type) {
+    case *ast.Identifier:
+        return node.Token.Line, node.Token.Column
+    case *ast.ExpressionStatement:
+        return node.Token.Line, node.Token.Column
+    case *ast.LetStatement:
+        return node.Token.Line, node.Token.Column
+    case *ast.ReturnStatement:
+        return node.Token.Line, node.Token.Column
+    case *ast.ThrowStatement:
+        return node.Token.Line, node.Token.Column
+    case *ast.DeferStatement:
+        return node.Token.Line, node.Token.Column
+    case *ast.FunctionStatement:
+        return node.Token.Line, node.Token.Column
+    case *ast.ImportStatement:
+        return node.Token.Line, node.Token.Column
+    case *ast.ExportStatement:
+        return node.Token.Line, node.Token.Column
+    case *ast.IfExpression:
+        return node.Token.Line, node.Token.Column
+    case *ast.CallExpression:
+        return position(node.Function)
+    }
+
+    return 0, 0
+}
+
+func (c *checker) check(program *ast.Program, globals []string) {
+    c.resolution = resolver.Resolve(program, evaluator.BuiltinNames(), globals...)
+    c.kinds = map[*ast.Identifier]bindingKind{}
+    c.piped = map[*ast.CallExpression]bool{}
+
+    ast.Inspect(program, c.visit)
+
+    c.checkUnused()
+    c.checkShadows()
+
+    for _, ident := range c.resolution.Undefined {
+        c.report(ident, UNDEFINED_NAME, "%s is not defined", ident.Value)
+    }
+}
+
+func (c *checker) visit(node ast.Node) bool {
+    switch node := node.(type) {
+    case *ast.Program:
+        c.checkReachable(node.Statements)
+    case *ast.BlockStatement:
+        c.checkReachable(node.Statements)
+    case *ast.LetStatement:
+        if node.Pattern != nil {
+            c.declarePattern(node.Pattern)
+        } else {
+            c.kinds[node.Name] = variable
+        }
+    case *ast.FunctionLiteral:
+        for _, param := range node.Parameters {
+            c.kinds[param] = parameter
+        }
+        if node.Rest != nil {
+            c.kinds[node.Rest] = parameter
+        }
+    case *ast.MacroLiteral:
+        for _, param := range node.Parameters {
+            c.kinds[param] = parameter
+        }
+    case *ast.IfExpression:
+        c.checkCondition(node)
+    case *ast.CallExpression:
+        if !c.piped[node] {
+            c.checkArity(node, node.Function, node.Arguments, 0)
+        }
+    case *ast.PipelineExpression:
+        if call, ok := node.Right.(*ast.CallExpression); ok {
+            c.piped[call] = true
+            c.checkArity(call, call.Function, call.Arguments, 1)
+        } else {
+            c.checkArity(node.Right, node.Right, nil, 1)
+        }
+    }
+
+    return true
+}
+
+func (c *checker) declarePattern(pattern ast.Expression) {
+    switch pattern := pattern.(type) {
+    case *ast.Identifier:
+        c.kinds[pattern] = variable
+    case *ast.DefaultPattern:
+        c.declarePattern(pattern.Pattern)
+    case *ast.ArrayPattern:
+        for _, el := range pattern.Elements {
+            c.declarePattern(el)
+        }
+        if pattern.Rest != nil {
+            c.kinds[pattern.Rest] = variable
+        }
+    case *ast.HashPattern:
+        for _, value := range pattern.Values {
+            c.declarePattern(value)
+        }
+    }
+}
+
+func (c *checker) checkReachable(statements []ast.Statement) {
+    for i := 1; i < len(statements); i++ {
+        switch statements[i-1].(type) {
+        case *ast.ReturnStatement, *ast.ThrowStatement:
+            c.report(statements[i], UNREACHABLE, "unreachable code after %s", statements[i-1].TokenLiteral())
+            return
+        }
+    }
+}
+
+// checkUnused reports let bindings and parameters that nothing refers to.
+// Names at the top level are visible to the REPL and importers, so only
+// local ones are checked.
+func (c *checker) checkUnused() {
+    used := map[*ast.Identifier]bool{}
+    for _, symbol := range c.resolution.References {
+        if symbol.Decl != nil {
+            used[symbol.Decl] = true
+        }
+    }
+
+    for decl, kind := range c.kinds {
+        symbol, ok := c.resolution.Definitions[decl]
+        if !ok || symbol.Scope == resolver.GlobalScope || used[decl] || strings.HasPrefix(decl.Value, "_") {
+            continue
+        }
+
+        switch kind {
+        case variable:
+            c.report(decl, UNUSED_VARIABLE, "%s is declared but never used", decl.Value)
+        case parameter:
+            c.report(decl, UNUSED_PARAMETER, "parameter %s is never used", decl.Value)
+        }
+    }
+}
+
+func (c *checker) checkShadows() {
+    for decl, hidden := range c.resolution.Shadows {
+        switch {
+        case hidden.Scope == resolver.BuiltinScope:
+            c.report(decl, SHADOW_BUILTIN, "%s shadows the builtin function %s", decl.Value, decl.Value)
+        case hidden.Decl == nil:
+            c.report(decl, SHADOW, "%s shadows the prelude function %s", decl.Value, decl.Value)
+        default:
+            tok := hidden.Decl.Token
+            c.report(decl, SHADOW, "%s shadows the binding declared at %d:%d", decl.Value, tok.Line, tok.Column)
+        }
+    }
+}
+
+// checkCondition reports an if whose condition is built only from literals.
+func (c *checker) checkCondition(ie *ast.IfExpression) {
+    value, ok := fold(ie.Condition)
+    if !ok {
+        return
+    }
+
+    c.report(ie, CONSTANT_CONDITION, "if condition is always %t", value != false)
+}
+
+// fold works out the value of an expression built from literals the way the
+// evaluator would, as an int64, bool or string. It reports false for
+// anything else, including operations that fail at runtime such as division
+// by zero.
+func fold(e ast.Expression) (interface{}, bool) {
+    switch e := e.(type) {
+    case *ast.IntegerLiteral:
+        return e.Value, true
+    case *ast.Boolean:
+        return e.Value, true
+    case *ast.StringLiteral:
+        return e.Value, true
+    case *ast.PrefixExpression:
+        right, ok := fold(e.Right)
+        if !ok {
+            return nil, false
+        }
+
+        switch e.Operator {
+        case "!":
+            return right == false, true
+        case "-":
+            if n, ok := right.(int64); ok {
+                return -n, true
+            }
+        }
+    case *ast.InfixExpression:
+        left, ok := fold(e.Left)
+        if !ok {
+            return nil, false
+        }
+        right, ok := fold(e.Right)
+        if !ok {
+            return nil, false
+        }
+
+        return foldInfix(e.Operator, left, right)
+    }
+
+    return nil, false
+}
+
+func foldInfix(operator string, left, right interface{}) (interface{}, bool) {
+    a, leftInt := left.(int64)
+    b, rightInt := right.(int64)
+    if leftInt && rightInt {
+        switch operator {
+        case "+":
+            return a + b, true
+        case "-":
+            return a - b, true
+        case "*":
+            return a * b, true
+        case "/":
+            if b == 0 {
+                return nil, false
+            }
+            return a / b, true
+        case "<":
+            return a < b, true
+        case ">":
+            return a > b, true
+        case "==":
+            return a == b, true
+        case "!=":
+            return a != b, true
+        }
+        return nil, false
+    }
+
+    x, leftString := left.(string)
+    y, rightString := right.(string)
+    if leftString && rightString {
+        if operator == "+" {
+            return x + y, true
+        }
+        return nil, false
+    }
+
+    //other values compare by identity, so only equal booleans are equal
+    switch operator {
+    case "==":
+        return left == right, true
+    case "!=":
+        return left != right, true
+    }
+
+    return nil, false
+}
+
+// checkArity reports a call of an unshadowed builtin with the wrong number of
+// arguments. implicit counts arguments supplied by a pipeline.
+func (c *checker) checkArity(call ast.Node, function ast.Expression, args []ast.Expression, implicit int) {
+    ident, ok := function.(*ast.Identifier)
+    if !ok || c.resolution.References[ident].Scope != resolver.BuiltinScope {
+        return
+    }
+
+    builtin, ok := evaluator.LookupBuiltin(ident.Value)
+    if !ok || builtin.Arity < 0 {
+        return
+    }
+
+    for _, arg := range args {
+        if _, ok := arg.(*ast.SpreadExpression); ok {
+            return
+        }
+    }
+
+    if got := len(args) + implicit; got != builtin.Arity {
+        c.report(call, BUILTIN_ARITY, "%s expects %d %s, got %d", ident.Value, builtin.Arity, plural(builtin.Arity, "argument"), got)
+    }
+}
+
+func plural(n int, word string) string {
+    if n == 1 {
+        return word
+    }
+    return word + "s"
+}
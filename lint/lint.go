@@ -0,0 +1,123 @@
+// Package lint reports likely mistakes in Elliott programs without running
+// them: unused bindings and parameters, unreachable code, shadowing,
+// constant if conditions, builtin calls with the wrong argument count and
+// undefined names.
+//
+// A finding is suppressed by a comment on the same line:
+//
+//	let unused = 1; // lint:ignore
+//	let len = 2; // lint:ignore shadow-builtin
+//
+// With check names after lint:ignore only those checks are suppressed.
+package lint
+
+import (
+    "errors"
+    "fmt"
+    "sort"
+    "strings"
+    "github.com/JakeNorman007/interpreter/ast"
+    "github.com/JakeNorman007/interpreter/lexer"
+    "github.com/JakeNorman007/interpreter/parser"
+    "github.com/JakeNorman007/interpreter/prelude"
+)
+
+// The checks, as named in findings and lint:ignore comments.
+const (
+    UNUSED_VARIABLE     = "unused-variable"
+    UNUSED_PARAMETER    = "unused-parameter"
+    UNREACHABLE         = "unreachable"
+    SHADOW              = "shadow"
+    SHADOW_BUILTIN      = "shadow-builtin"
+    CONSTANT_CONDITION  = "constant-condition"
+    BUILTIN_ARITY       = "builtin-arity"
+    UNDEFINED_NAME      = "undefined-name"
+)
+
+type Finding struct {
+    Check   string  `json:"check"`
+    Message string  `json:"message"`
+    Line    int     `json:"line"`
+    Column  int     `json:"column"`
+}
+
+func (f Finding) String() string {
+    return fmt.Sprintf("%d:%d: %s (%s)", f.Line, f.Column, f.Message, f.Check)
+}
+
+// Source parses src and lints it, dropping findings silenced by a
+// lint:ignore comment. Parse errors are returned as the error.
+func Source(src []byte) ([]Finding, error) {
+    l := lexer.New(string(src))
+    p := parser.New(l)
+
+    program := p.ParseProgram()
+    if len(p.Errors()) != 0 {
+        return nil, errors.New(strings.Join(p.Errors(), "\n"))
+    }
+
+    ignored := ignoredChecks(l)
+    findings := []Finding{}
+    for _, f := range Program(program) {
+        if !isIgnored(ignored, f) {
+            findings = append(findings, f)
+        }
+    }
+
+    return findings, nil
+}
+
+// Program lints program, returning the findings ordered by position. The
+// prelude's functions count as defined.
+func Program(program *ast.Program) []Finding {
+    c := &checker{}
+    c.check(program, prelude.Names())
+
+    sort.SliceStable(c.findings, func(i, j int) bool {
+        a, b := c.findings[i], c.findings[j]
+        if a.Line != b.Line {
+            return a.Line < b.Line
+        }
+        return a.Column < b.Column
+    })
+
+    return c.findings
+}
+
+// ignoredChecks maps each line with a lint:ignore comment to the checks it
+// names, or to an empty list when it silences every check.
+func ignoredChecks(l *lexer.Lexer) map[int][]string {
+    ignored := map[int][]string{}
+
+    for _, comment := range l.Comments() {
+        text := strings.TrimSpace(strings.TrimPrefix(comment.Literal, "//"))
+        if !strings.HasPrefix(text, "lint:ignore") {
+            continue
+        }
+
+        checks := strings.FieldsFunc(strings.TrimPrefix(text, "lint:ignore"), func(r rune) bool {
+            return r == ' ' || r == ','
+        })
+        ignored[comment.Line] = append([]string{}, checks...)
+    }
+
+    return ignored
+}
+
+func isIgnored(ignored map[int][]string, f Finding) bool {
+    checks, ok := ignored[f.Line]
+    if !ok {
+        return false
+    }
+    if len(checks) == 0 {
+        return true
+    }
+
+    for _, check := range checks {
+        if check == f.Check {
+            return true
+        }
+    }
+
+    return false
+}
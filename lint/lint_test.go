@@ -0,0 +1,104 @@
+package lint
+
+import (
+    "reflect"
+    "testing"
+)
+
+func TestLint(t *testing.T) {
+    tests := []struct {
+        input       string
+        expected    []string
+    }{
+        {"let f = func(a, b) { let c = 1; a }; f(1, 2)", []string{
+            "1:17: parameter b is never used (unused-parameter)",
+            "1:26: c is declared but never used (unused-variable)",
+        }},
+        {"let top = 1; let f = func(_a) { let _b = 2; 0 }", []string{}},
+        {"let f = func(x) { let y = x; let y = 2; y }", []string{"1:23: y is declared but never used (unused-variable)"}},
+        {"let f = func() { g() }; let g = func() { 1 }", []string{}},
+        {"let f = func(x, y = x) { y }", []string{}},
+        {"let f = func() { return 1; print(2); print(3) }", []string{"1:28: unreachable code after return (unreachable)"}},
+        {"let f = func() { if (true) { throw \"x\"; 1 } }", []string{
+            "1:18: if condition is always true (constant-condition)",
+            "1:41: unreachable code after throw (unreachable)",
+        }},
+        {"if (!(1 > 2)) { 1 }; if (\"a\" + \"b\") { 2 }; if (x) { 3 }; if (1 / 0) { 4 }", []string{
+            "1:1: if condition is always true (constant-condition)",
+            "1:22: if condition is always true (constant-condition)",
+            "1:48: x is not defined (undefined-name)",
+        }},
+        {"if (10 / 2 > 4) { 1 }; if (1 == true) { 2 }; if (!-1) { 3 }; if (\"a\" == \"a\") { 4 }; if (1 / (2 - 2) > 0) { 5 }", []string{
+            "1:1: if condition is always true (constant-condition)",
+            "1:24: if condition is always false (constant-condition)",
+            "1:46: if condition is always false (constant-condition)",
+        }},
+        {"let x = 1; let f = func(x) { x }; let g = func() { let x = 2; x }", []string{
+            "1:25: x shadows the binding declared at 1:5 (shadow)",
+            "1:56: x shadows the binding declared at 1:5 (shadow)",
+        }},
+        {"let first = 1; let f = func(len) { len }", []string{
+            "1:5: first shadows the builtin function first (shadow-builtin)",
+            "1:29: len shadows the builtin function len (shadow-builtin)",
+        }},
+        {"let xs = [1]; [x for x in xs]; match (xs) { [xs] => xs }", []string{
+            "1:46: xs shadows the binding declared at 1:5 (shadow)",
+        }},
+        {"try { 1 } catch (e) { 0 }; let [a, {b}] = [1, {\"b\": 2}]", []string{}},
+        {"len(1, 2); push([]); print(); push(...xs); [1] |> push(2); [1] |> len; 1 |> push", []string{
+            "1:1: len expects 1 argument, got 2 (builtin-arity)",
+            "1:12: push expects 2 arguments, got 1 (builtin-arity)",
+            "1:39: xs is not defined (undefined-name)",
+            "1:77: push expects 2 arguments, got 1 (builtin-arity)",
+        }},
+        {"let f = func(len) { len(1, 2) }; f(1)", []string{"1:14: len shadows the builtin function len (shadow-builtin)"}},
+        {"map([1], func(x) { x + y }); let f = func() { g }; let g = 1", []string{"1:24: y is not defined (undefined-name)"}},
+        {"let sum = func(xs) { reduce(xs, func(a, b) { a + b }, 0) }", []string{"1:5: sum shadows the prelude function sum (shadow)"}},
+    }
+
+    for _, tt := range tests {
+        findings, err := Source([]byte(tt.input))
+        if err != nil {
+            t.Fatalf("%s: %s", tt.input, err)
+        }
+
+        got := []string{}
+        for _, f := range findings {
+            got = append(got, f.String())
+        }
+
+        if !reflect.DeepEqual(got, tt.expected) {
+            t.Errorf("%s:\nwant=%q\ngot=%q", tt.input, tt.expected, got)
+        }
+    }
+}
+
+func TestLintIgnore(t *testing.T) {
+    input := `let f = func(a, b) {
+    let c = 1; // lint:ignore
+    let d = 2; // lint:ignore unused-parameter
+    let e = 3; // lint:ignore shadow, unused-variable
+    0
+}; f`
+
+    findings, err := Source([]byte(input))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    expected := []Finding{
+        {Check: UNUSED_PARAMETER, Message: "parameter a is never used", Line: 1, Column: 14},
+        {Check: UNUSED_PARAMETER, Message: "parameter b is never used", Line: 1, Column: 17},
+        {Check: UNUSED_VARIABLE, Message: "d is declared but never used", Line: 3, Column: 9},
+    }
+
+    if !reflect.DeepEqual(findings, expected) {
+        t.Errorf("wrong findings.\nwant=%v\ngot=%v", expected, findings)
+    }
+}
+
+func TestLintParseError(t *testing.T) {
+    if _, err := Source([]byte("let = 1")); err == nil {
+        t.Errorf("expected a parse error")
+    }
+}
@@ -3,6 +3,7 @@ package main
 import (
     "os"
     "fmt"
+    "flag"
     "os/user"
     "github.com/JakeNorman007/interpreter/repl"
     "github.com/charmbracelet/lipgloss"
@@ -23,11 +24,36 @@ Use Ctrl+C to exit.
 var logoStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#01FAC6"))
 
 func main() {
+    if len(os.Args) > 1 {
+        switch os.Args[1] {
+        case "fmt":
+            os.Exit(runFmt(os.Args[2:]))
+        case "parse":
+            os.Exit(runParse(os.Args[2:]))
+        case "lint":
+            os.Exit(runLint(os.Args[2:]))
+        }
+    }
+
+    strict := flag.Bool("strict", false, "run in strict mode")
+    noPrelude := flag.Bool("no-prelude", false, "do not load the standard prelude")
+    flag.Parse()
+
+    opts := repl.Options{Strict: *strict, NoPrelude: *noPrelude}
+
+    if flag.NArg() > 0 {
+        if err := repl.RunFile(flag.Arg(0), opts); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(1)
+        }
+        return
+    }
+
      _, err := user.Current()
     if err != nil {
         panic(err)
     }
 
     fmt.Printf("%s\n", logoStyle.Render(logo))
-    repl.Start(os.Stdin, os.Stdout)
+    repl.Start(os.Stdin, os.Stdout, opts)
 }
@@ -1,8 +1,38 @@
 package object
 
+import (
+    "sort"
+    "github.com/JakeNorman007/interpreter/ast"
+)
+
 type Environment struct {
-    store   map[string]Object
-    outer   *Environment
+    store       map[string]Object
+    consts      map[string]bool
+    outer       *Environment
+    strict      bool
+    function    bool //true for the environment of a function call
+    module      bool //true for the top-level scope of a script or module
+    deferred    []Deferred
+    dir         string //directory imports resolve against, "" for the working directory
+    exports     map[string]bool
+    loader      *Loader //the imports of the run this module scope belongs to
+}
+
+// Loader holds the modules imported during one run of a program, so each
+// file is evaluated once per run and import cycles can be reported.
+type Loader struct {
+    Modules     map[string]*Module //modules that finished evaluating, keyed by absolute path
+    Loading     []string //modules currently being evaluated, outermost (the entry script, if any) first
+}
+
+// NewLoader returns a loader that has imported nothing yet.
+func NewLoader() *Loader {
+    return &Loader{Modules: make(map[string]*Module)}
+}
+
+type Deferred struct {
+    Expression  ast.Expression
+    Env         *Environment
 }
 
 func NewEnclosedEnvironment(outer *Environment) *Environment {
@@ -12,9 +42,25 @@ func NewEnclosedEnvironment(outer *Environment) *Environment {
     return env
 }
 
+func NewFunctionEnvironment(outer *Environment) *Environment {
+    env := NewEnclosedEnvironment(outer)
+    env.function = true
+
+    return env
+}
+
+// NewModuleEnvironment returns the top-level scope for a script or module,
+// enclosed by base (typically the prelude), which may be nil.
+func NewModuleEnvironment(base *Environment) *Environment {
+    env := NewEnclosedEnvironment(base)
+    env.module = true
+
+    return env
+}
+
 func NewEnvironment() *Environment {
     s := make(map[string]Object)
-    return &Environment{store: s, outer: nil}
+    return &Environment{store: s, consts: make(map[string]bool), outer: nil}
 }
 
 func (e *Environment) Get(name string) (Object, bool) {
@@ -29,3 +75,144 @@ func (e *Environment) Set(name string, val Object) Object {
     e.store[name] = val
     return val
 }
+
+func (e *Environment) SetConst(name string, val Object) Object {
+    e.consts[name] = true
+    return e.Set(name, val)
+}
+
+// IsConst reports whether name is a const binding in this scope, not
+// counting outer scopes.
+func (e *Environment) IsConst(name string) bool {
+    return e.consts[name]
+}
+
+// Has reports whether name is bound in this scope, not counting outer scopes.
+func (e *Environment) Has(name string) bool {
+    _, ok := e.store[name]
+    return ok
+}
+
+// Names returns the names bound in this scope in sorted order.
+func (e *Environment) Names() []string {
+    names := make([]string, 0, len(e.store))
+    for name := range e.store {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    return names
+}
+
+func (e *Environment) SetStrict(strict bool) {
+    e.strict = strict
+}
+
+// Strict reports whether strict mode is on for this scope or any scope
+// enclosing it.
+func (e *Environment) Strict() bool {
+    for env := e; env != nil; env = env.outer {
+        if env.strict {
+            return true
+        }
+    }
+
+    return false
+}
+
+// SetDir sets the directory that imports evaluated in e resolve against.
+func (e *Environment) SetDir(dir string) {
+    e.dir = dir
+}
+
+// Dir returns the import directory of e or the nearest scope enclosing it.
+func (e *Environment) Dir() string {
+    for env := e; env != nil; env = env.outer {
+        if env.dir != "" {
+            return env.dir
+        }
+    }
+
+    return ""
+}
+
+// SetLoader makes imports evaluated in e, and in scopes it encloses, use
+// loader.
+func (e *Environment) SetLoader(loader *Loader) {
+    e.loader = loader
+}
+
+// Loader returns the loader of e or the nearest scope enclosing it. If there
+// is none, a new one is attached to e's module scope, or to e itself outside
+// any module.
+func (e *Environment) Loader() *Loader {
+    owner := e
+    for env := e; env != nil; env = env.outer {
+        if env.loader != nil {
+            return env.loader
+        }
+        if env.module {
+            owner = env
+            break
+        }
+    }
+
+    owner.loader = NewLoader()
+    return owner.loader
+}
+
+// Base returns the environment enclosing the module scope e belongs to, or
+// nil if e is not inside a module scope.
+func (e *Environment) Base() *Environment {
+    for env := e; env != nil; env = env.outer {
+        if env.module {
+            return env.outer
+        }
+    }
+
+    return nil
+}
+
+// Export marks name as visible to importers of the module e belongs to. It
+// reports false when e is not a module's top-level scope.
+func (e *Environment) Export(name string) bool {
+    if !e.module {
+        return false
+    }
+
+    if e.exports == nil {
+        e.exports = make(map[string]bool)
+    }
+    e.exports[name] = true
+
+    return true
+}
+
+func (e *Environment) IsExported(name string) bool {
+    return e.exports[name]
+}
+
+// Defer records exp to be evaluated in e when the enclosing function call
+// returns. It reports false when e is not inside a function call.
+func (e *Environment) Defer(exp ast.Expression) bool {
+    for frame := e; frame != nil; frame = frame.outer {
+        if frame.function {
+            frame.deferred = append(frame.deferred, Deferred{Expression: exp, Env: e})
+            return true
+        }
+    }
+
+    return false
+}
+
+// TakeDeferred returns the deferred expressions of a function call
+// environment in the order they should run, last deferred first.
+func (e *Environment) TakeDeferred() []Deferred {
+    deferred := make([]Deferred, 0, len(e.deferred))
+    for i := len(e.deferred) - 1; i >= 0; i-- {
+        deferred = append(deferred, e.deferred[i])
+    }
+    e.deferred = nil
+
+    return deferred
+}
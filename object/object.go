@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"bytes"
     "hash/fnv"
+	"math"
 	"strings"
 	"github.com/JakeNorman007/interpreter/ast"
 )
@@ -21,6 +22,10 @@ const (
     BUILTIN_OBJ = "BUILTIN"
     ARRAY_OBJ = "ARRAY"
     HASH_OBJ = "HASH"
+    RANGE_OBJ = "RANGE"
+    MODULE_OBJ = "MODULE"
+    QUOTE_OBJ = "QUOTE"
+    MACRO_OBJ = "MACRO"
 )
 
 type Object interface {
@@ -54,15 +59,44 @@ type ReturnValue struct {
 func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
 func (rv *ReturnValue) Inspect() string { return rv.Value.Inspect() }
 
+const (
+    RUNTIME_ERROR = "RuntimeError"
+    TYPE_ERROR = "TypeError"
+    NAME_ERROR = "NameError"
+    ARGUMENT_ERROR = "ArgumentError"
+    MATCH_ERROR = "MatchError"
+    IMPORT_ERROR = "ImportError"
+    THROWN_ERROR = "Error"
+)
+
 type Error struct {
+    Kind    string
     Message string
+    Payload Object //the value passed to throw, nil for errors raised by the interpreter
+    Stack   []string //names of the functions the error unwound through, innermost first
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
 func (e *Error) Inspect() string { return "ERROR:" + e.Message }
 
+// Trace returns Inspect followed by a line for each function in Stack, for
+// reporting an error that reached the top level.
+func (e *Error) Trace() string {
+    var out bytes.Buffer
+
+    out.WriteString(e.Inspect())
+    for _, name := range e.Stack {
+        out.WriteString("\n\tin " + name)
+    }
+
+    return out.String()
+}
+
 type Function struct {
+    Name        string
     Parameters  []*ast.Identifier
+    Defaults    []ast.Expression
+    Rest        *ast.Identifier
     Body        *ast.BlockStatement
     Env         *Environment
 }
@@ -71,12 +105,12 @@ func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
 func (f *Function) Inspect() string {
     var out bytes.Buffer
 
-    params := []string{}
-    for _, p := range f.Parameters {
-        params = append(params, p.String())
-    }
+    params := ast.ParameterStrings(f.Parameters, f.Defaults, f.Rest)
 
     out.WriteString("func")
+    if f.Name != "" {
+        out.WriteString(" " + f.Name)
+    }
     out.WriteString("(")
     out.WriteString(strings.Join(params, ", "))
     out.WriteString(") {\n")
@@ -96,7 +130,8 @@ func (s *String) Inspect() string { return s.Value }
 type BuiltInFunction func(args ...Object) Object
 
 type Builtin struct {
-    Fn  BuiltInFunction
+    Fn      BuiltInFunction
+    Arity   int //the number of arguments Fn expects, or -1 for any number
 }
 
 func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
@@ -155,8 +190,34 @@ type HashPair struct {
     Value   Object
 }
 
+// Hash maps keys to values and remembers the order keys were added in, so
+// printing and iterating a hash is the same on every run.
 type Hash struct {
     Pairs   map[HashKey]HashPair
+    Keys    []HashKey //the keys of Pairs in insertion order
+}
+
+// NewHash returns an empty hash.
+func NewHash() *Hash {
+    return &Hash{Pairs: make(map[HashKey]HashPair)}
+}
+
+// Set adds pair under key. Replacing a key's pair keeps its position.
+func (h *Hash) Set(key HashKey, pair HashPair) {
+    if _, ok := h.Pairs[key]; !ok {
+        h.Keys = append(h.Keys, key)
+    }
+    h.Pairs[key] = pair
+}
+
+// Ordered returns the pairs in insertion order.
+func (h *Hash) Ordered() []HashPair {
+    pairs := make([]HashPair, 0, len(h.Keys))
+    for _, key := range h.Keys {
+        pairs = append(pairs, h.Pairs[key])
+    }
+
+    return pairs
 }
 
 func (h *Hash) Type() ObjectType { return HASH_OBJ }
@@ -164,7 +225,7 @@ func (h *Hash) Inspect() string {
     var out bytes.Buffer
 
     pairs := []string{}
-    for _, pair := range h.Pairs {
+    for _, pair := range h.Ordered() {
         pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
     }
 
@@ -178,3 +239,105 @@ func (h *Hash) Inspect() string {
 type Hashable interface {
     HashKey()   HashKey
 }
+
+// Range is a lazy sequence of consecutive integers. It never allocates its
+// elements; End is exclusive unless Inclusive is set.
+type Range struct {
+    Start       int64
+    End         int64
+    Inclusive   bool
+}
+
+func (r *Range) Type() ObjectType { return RANGE_OBJ }
+func (r *Range) Inspect() string {
+    if r.Inclusive {
+        return fmt.Sprintf("%d..%d", r.Start, r.End)
+    }
+
+    return fmt.Sprintf("%d..<%d", r.Start, r.End)
+}
+
+// Len returns the number of integers in r, saturating at math.MaxInt64 for
+// ranges too long to count in an int64.
+func (r *Range) Len() int64 {
+    if r.End < r.Start || r.End == r.Start && !r.Inclusive {
+        return 0
+    }
+
+    //End - Start overflows when the bounds are far apart
+    if r.Start < 0 && r.End > math.MaxInt64 + r.Start {
+        return math.MaxInt64
+    }
+
+    n := r.End - r.Start
+    if r.Inclusive {
+        if n == math.MaxInt64 {
+            return n
+        }
+        n++
+    }
+
+    return n
+}
+
+func (r *Range) At(i int64) int64 { return r.Start + i }
+
+// Last returns the final integer in r. It is only meaningful when r is not
+// empty.
+func (r *Range) Last() int64 {
+    if r.Inclusive {
+        return r.End
+    }
+
+    return r.End - 1
+}
+
+func (r *Range) Contains(v int64) bool {
+    if r.Inclusive {
+        return v >= r.Start && v <= r.End
+    }
+
+    return v >= r.Start && v < r.End
+}
+
+// Module is an imported file. Its top-level bindings live in Env, and only
+// the exported ones are reachable from outside.
+type Module struct {
+    Path        string
+    Env         *Environment
+}
+
+func (m *Module) Type() ObjectType { return MODULE_OBJ }
+func (m *Module) Inspect() string { return "<module " + m.Path + ">" }
+
+// Quote wraps an unevaluated AST node, produced by quote(...).
+type Quote struct {
+    Node        ast.Node
+}
+
+func (q *Quote) Type() ObjectType { return QUOTE_OBJ }
+func (q *Quote) Inspect() string { return "QUOTE(" + q.Node.String() + ")" }
+
+type Macro struct {
+    Parameters  []*ast.Identifier
+    Body        *ast.BlockStatement
+    Env         *Environment
+}
+
+func (m *Macro) Type() ObjectType { return MACRO_OBJ }
+func (m *Macro) Inspect() string {
+    var out bytes.Buffer
+
+    params := []string{}
+    for _, p := range m.Parameters {
+        params = append(params, p.String())
+    }
+
+    out.WriteString("macro(")
+    out.WriteString(strings.Join(params, ", "))
+    out.WriteString(") {\n")
+    out.WriteString(m.Body.String())
+    out.WriteString("\n}")
+
+    return out.String()
+}
@@ -1,6 +1,10 @@
 package object
 
-import "testing"
+import (
+    "math"
+    "testing"
+    "github.com/JakeNorman007/interpreter/ast"
+)
 
 func TestStringHashKey(t *testing.T) {
     hello1 := &String{Value: "Hello World"}
@@ -20,3 +24,78 @@ func TestStringHashKey(t *testing.T) {
         t.Errorf("strings with same content have different hash keys")
     }
 }
+
+func TestEnvironmentDefer(t *testing.T) {
+    global := NewEnvironment()
+    if global.Defer(nil) {
+        t.Errorf("Defer outside a function call should report false")
+    }
+
+    call := NewFunctionEnvironment(global)
+    block := NewEnclosedEnvironment(call)
+
+    first := &ast.Identifier{Value: "first"}
+    second := &ast.Identifier{Value: "second"}
+
+    if !call.Defer(first) || !block.Defer(second) {
+        t.Fatalf("Defer inside a function call should report true")
+    }
+
+    deferred := call.TakeDeferred()
+    if len(deferred) != 2 {
+        t.Fatalf("TakeDeferred returned %d entries, want 2", len(deferred))
+    }
+
+    if deferred[0].Expression != second || deferred[0].Env != block {
+        t.Errorf("deferred[0] is not the last deferred expression, got=%+v", deferred[0])
+    }
+
+    if deferred[1].Expression != first || deferred[1].Env != call {
+        t.Errorf("deferred[1] is not the first deferred expression, got=%+v", deferred[1])
+    }
+
+    if len(call.TakeDeferred()) != 0 {
+        t.Errorf("TakeDeferred should empty the deferred list")
+    }
+}
+
+func TestRange(t *testing.T) {
+    tests := []struct {
+        r           *Range
+        length      int64
+        contains    []int64
+        excludes    []int64
+        inspect     string
+    }{
+        {&Range{Start: 1, End: 5, Inclusive: true}, 5, []int64{1, 3, 5}, []int64{0, 6}, "1..5"},
+        {&Range{Start: 1, End: 5}, 4, []int64{1, 4}, []int64{0, 5}, "1..<5"},
+        {&Range{Start: 5, End: 1, Inclusive: true}, 0, nil, []int64{1, 3, 5}, "5..1"},
+        {&Range{Start: 3, End: 3}, 0, nil, []int64{3}, "3..<3"},
+        {&Range{Start: math.MinInt64, End: math.MaxInt64, Inclusive: true}, math.MaxInt64,
+            []int64{math.MinInt64, 0, math.MaxInt64}, nil, "-9223372036854775808..9223372036854775807"},
+        {&Range{Start: 0, End: math.MaxInt64, Inclusive: true}, math.MaxInt64, []int64{math.MaxInt64}, []int64{-1}, "0..9223372036854775807"},
+        {&Range{Start: -5, End: math.MaxInt64}, math.MaxInt64, []int64{-5}, []int64{math.MaxInt64}, "-5..<9223372036854775807"},
+    }
+
+    for _, tt := range tests {
+        if tt.r.Len() != tt.length {
+            t.Errorf("%s: Len() is %d, want %d", tt.inspect, tt.r.Len(), tt.length)
+        }
+
+        for _, v := range tt.contains {
+            if !tt.r.Contains(v) {
+                t.Errorf("%s: should contain %d", tt.inspect, v)
+            }
+        }
+
+        for _, v := range tt.excludes {
+            if tt.r.Contains(v) {
+                t.Errorf("%s: should not contain %d", tt.inspect, v)
+            }
+        }
+
+        if tt.r.Inspect() != tt.inspect {
+            t.Errorf("Inspect() is %q, want %q", tt.r.Inspect(), tt.inspect)
+        }
+    }
+}
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"io"
 	"strconv"
 	"github.com/JakeNorman007/interpreter/ast"
 	"github.com/JakeNorman007/interpreter/lexer"
@@ -11,8 +12,11 @@ import (
 const (
     _ int = iota
     LOWEST
+    PIPE            //|>
+    COALESCE        //??
     EQUALS          //==
     LESSGREATER     //> or <
+    RANGE           //.. or ..<
     SUM             //+
     PRODUCT         //*
     PREFIX          //-X or !X
@@ -27,6 +31,10 @@ type Parser struct {
     errors          []string
     prefixParseFns  map[token.TokenType]prefixParseFn
     infixParseFns   map[token.TokenType]infixParseFn
+    constScopes     []map[string]bool //const names declared in each enclosing function scope
+    noArrow         bool //set while parsing a match guard, where => ends the guard, and cleared inside brackets
+    tracer          io.Writer //where trace output goes, nil unless the Trace option is set
+    traceLevel      int
 }
 
 var precedences = map[token.TokenType]int {
@@ -40,6 +48,13 @@ var precedences = map[token.TokenType]int {
     token.ASTERISK:     PRODUCT,
     token.LEFTPAREN:    CALL,
     token.LEFTBRACKET:  INDEX,
+    token.DOT:          INDEX,
+    token.OPTIONAL_CHAIN: INDEX,
+    token.COALESCE:     COALESCE,
+    token.PIPE:         PIPE,
+    token.RANGE:        RANGE,
+    token.RANGE_EXCLUSIVE: RANGE,
+    token.IN:           LESSGREATER,
 }
 
 func (p *Parser) peepPrecedence() int {
@@ -58,8 +73,12 @@ func (p *Parser) curPrecedence() int {
     return LOWEST
 }
 
-func New(l *lexer.Lexer) *Parser {
+func New(l *lexer.Lexer, opts ...Option) *Parser {
     p := &Parser{l: l, errors: []string{},}
+    for _, opt := range opts {
+        opt(p)
+    }
+    p.pushConstScope()
 
     p.nextToken()
     p.nextToken()
@@ -74,9 +93,13 @@ func New(l *lexer.Lexer) *Parser {
     p.registerPrefix(token.LEFTPAREN, p.parseGroupedExpression)
     p.registerPrefix(token.IF, p.parseIfExpression)
     p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
+    p.registerPrefix(token.MACRO, p.parseMacroLiteral)
     p.registerPrefix(token.STRING, p.parseStringLiteral)
     p.registerPrefix(token.LEFTBRACKET, p.parseArrayLiteral)
     p.registerPrefix(token.LEFTBRACE, p.parseHashLiteral)
+    p.registerPrefix(token.ELLIPSIS, p.parseSpreadExpression)
+    p.registerPrefix(token.MATCH, p.parseMatchExpression)
+    p.registerPrefix(token.TRY, p.parseTryExpression)
 
     p.infixParseFns = make(map[token.TokenType]infixParseFn)
     p.registerInfix(token.PLUS, p.parseInfixExpression)
@@ -89,13 +112,22 @@ func New(l *lexer.Lexer) *Parser {
     p.registerInfix(token.GREATERTHAN, p.parseInfixExpression)
     p.registerInfix(token.LEFTPAREN, p.parseCallExpression)
     p.registerInfix(token.LEFTBRACKET, p.parseIndexExpression)
+    p.registerInfix(token.DOT, p.parsePropertyExpression)
+    p.registerInfix(token.OPTIONAL_CHAIN, p.parseOptionalChainExpression)
+    p.registerInfix(token.COALESCE, p.parseCoalesceExpression)
+    p.registerInfix(token.PIPE, p.parsePipelineExpression)
+    p.registerInfix(token.RANGE, p.parseRangeExpression)
+    p.registerInfix(token.RANGE_EXCLUSIVE, p.parseRangeExpression)
+    p.registerInfix(token.IN, p.parseInfixExpression)
 
     return p
 }
 
 func (p *Parser) parseHashLiteral() ast.Expression {
+    defer p.restoreArrows(p.allowArrows())
+
     hash := &ast.HashLiteral{Token: p.curToken}
-    hash.Pairs = make(map[ast.Expression]ast.Expression)
+    hash.Pairs = []ast.HashPair{}
 
     for !p.peepTokenIs(token.RIGHTBRACE) {
         p.nextToken()
@@ -108,7 +140,11 @@ func (p *Parser) parseHashLiteral() ast.Expression {
         p.nextToken()
         value := p.parseExpression(LOWEST)
 
-        hash.Pairs[key] = value
+        if len(hash.Pairs) == 0 && p.peepTokenIs(token.FOR) {
+            return p.parseHashComprehension(hash.Token, key, value)
+        }
+
+        hash.Pairs = append(hash.Pairs, ast.HashPair{Key: key, Value: value})
 
         if !p.peepTokenIs(token.RIGHTBRACE) && !p.expectPeep(token.COMMA) {
             return nil
@@ -119,16 +155,27 @@ func (p *Parser) parseHashLiteral() ast.Expression {
     if !p.expectPeep(token.RIGHTBRACE) {
         return nil
     }
+    hash.End = p.curToken
 
     return hash
 }
 
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+    defer p.restoreArrows(p.allowArrows())
+
     exp := &ast.IndexExpression{Token: p.curToken, Left: left}
-    
+
+    if p.peepTokenIs(token.COLON) {
+        return p.parseSliceExpression(exp.Token, left, nil)
+    }
+
     p.nextToken()
     exp.Index = p.parseExpression(LOWEST)
 
+    if p.peepTokenIs(token.COLON) {
+        return p.parseSliceExpression(exp.Token, left, exp.Index)
+    }
+
     if !p.expectPeep(token.RIGHTBRACKET) {
         return nil
     }
@@ -136,36 +183,232 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
     return exp
 }
 
-func (p *Parser) parseArrayLiteral() ast.Expression {
-    array := &ast.ArrayLiteral{Token: p.curToken}
+func (p *Parser) parsePropertyExpression(left ast.Expression) ast.Expression {
+    exp := &ast.PropertyExpression{Token: p.curToken, Object: left}
 
-    array.Elements = p.parseExpressionList(token.RIGHTBRACKET)
+    if !p.expectPeep(token.IDENT) {
+        return nil
+    }
 
-    return array
+    exp.Property = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+    return exp
 }
 
-func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
-    list := []ast.Expression{}
+func (p *Parser) parseOptionalChainExpression(left ast.Expression) ast.Expression {
+    tok := p.curToken
+
+    if p.peepTokenIs(token.LEFTBRACKET) {
+        defer p.restoreArrows(p.allowArrows())
+
+        exp := &ast.OptionalIndexExpression{Token: tok, Left: left}
 
-    if p.peepTokenIs(end) {
         p.nextToken()
-        return list
+        if p.peepTokenIs(token.COLON) {
+            return p.parseOptionalSliceExpression(tok, left, nil)
+        }
+
+        p.nextToken()
+        exp.Index = p.parseExpression(LOWEST)
+
+        if p.peepTokenIs(token.COLON) {
+            return p.parseOptionalSliceExpression(tok, left, exp.Index)
+        }
+
+        if !p.expectPeep(token.RIGHTBRACKET) {
+            return nil
+        }
+
+        return exp
+    }
+
+    exp := &ast.OptionalPropertyExpression{Token: tok, Object: left}
+
+    if !p.expectPeep(token.IDENT) {
+        return nil
     }
 
+    exp.Property = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+    return exp
+}
+
+func (p *Parser) parseCoalesceExpression(left ast.Expression) ast.Expression {
+    exp := &ast.CoalesceExpression{Token: p.curToken, Left: left}
+
+    precedence := p.curPrecedence()
+    p.nextToken()
+    exp.Right = p.parseExpression(precedence)
+
+    return exp
+}
+
+func (p *Parser) parsePipelineExpression(left ast.Expression) ast.Expression {
+    exp := &ast.PipelineExpression{Token: p.curToken, Left: left}
+
+    precedence := p.curPrecedence()
+    p.nextToken()
+    exp.Right = p.parseExpression(precedence)
+
+    return exp
+}
+
+func (p *Parser) parseRangeExpression(left ast.Expression) ast.Expression {
+    exp := &ast.RangeExpression{
+        Token:      p.curToken,
+        Start:      left,
+        Inclusive:  p.curTokenIs(token.RANGE),
+    }
+
+    precedence := p.curPrecedence()
+    p.nextToken()
+    exp.End = p.parseExpression(precedence)
+
+    return exp
+}
+
+func (p *Parser) parseSliceExpression(tok token.Token, left, start ast.Expression) ast.Expression {
+    exp := &ast.SliceExpression{Token: tok, Left: left, Start: start}
+
     p.nextToken()
-    list = append(list, p.parseExpression(LOWEST))
+
+    if !p.peepTokenIs(token.COLON) && !p.peepTokenIs(token.RIGHTBRACKET) {
+        p.nextToken()
+        exp.End = p.parseExpression(LOWEST)
+    }
+
+    if p.peepTokenIs(token.COLON) {
+        p.nextToken()
+
+        if !p.peepTokenIs(token.RIGHTBRACKET) {
+            p.nextToken()
+            exp.Step = p.parseExpression(LOWEST)
+        }
+    }
+
+    if !p.expectPeep(token.RIGHTBRACKET) {
+        return nil
+    }
+
+    return exp
+}
+
+func (p *Parser) parseOptionalSliceExpression(tok token.Token, left, start ast.Expression) ast.Expression {
+    exp := p.parseSliceExpression(tok, left, start)
+    if slice, ok := exp.(*ast.SliceExpression); ok {
+        slice.Optional = true
+    }
+
+    return exp
+}
+
+func (p *Parser) parseArrayLiteral() ast.Expression {
+    defer p.restoreArrows(p.allowArrows())
+
+    array := &ast.ArrayLiteral{Token: p.curToken, Elements: []ast.Expression{}}
+
+    if p.peepTokenIs(token.RIGHTBRACKET) {
+        p.nextToken()
+        array.End = p.curToken
+        return array
+    }
+
+    p.nextToken()
+    first := p.parseExpression(LOWEST)
+
+    if p.peepTokenIs(token.FOR) {
+        return p.parseListComprehension(array.Token, first)
+    }
+
+    array.Elements = append(array.Elements, first)
 
     for p.peepTokenIs(token.COMMA) {
         p.nextToken()
         p.nextToken()
-        list = append(list, p.parseExpression(LOWEST))
+        array.Elements = append(array.Elements, p.parseExpression(LOWEST))
     }
 
-    if !p.expectPeep(end) {
+    if !p.expectPeep(token.RIGHTBRACKET) {
+        return nil
+    }
+    array.End = p.curToken
+
+    return array
+}
+
+func (p *Parser) parseListComprehension(tok token.Token, element ast.Expression) ast.Expression {
+    lc := &ast.ListComprehension{Token: tok, Element: element}
+
+    lc.Variables, lc.Iterable, lc.Condition = p.parseComprehensionClause()
+    if lc.Variables == nil {
+        return nil
+    }
+
+    if !p.expectPeep(token.RIGHTBRACKET) {
+        return nil
+    }
+
+    return lc
+}
+
+func (p *Parser) parseHashComprehension(tok token.Token, key, value ast.Expression) ast.Expression {
+    hc := &ast.HashComprehension{Token: tok, Key: key, Value: value}
+
+    hc.Variables, hc.Iterable, hc.Condition = p.parseComprehensionClause()
+    if hc.Variables == nil {
+        return nil
+    }
+
+    if !p.expectPeep(token.RIGHTBRACE) {
         return nil
     }
 
-    return list
+    return hc
+}
+
+//parses "for a[, b] in iterable [if condition]" with peepToken on for,
+//returning nil variables on error
+func (p *Parser) parseComprehensionClause() ([]*ast.Identifier, ast.Expression, ast.Expression) {
+    p.nextToken()
+
+    if !p.expectPeep(token.IDENT) {
+        return nil, nil, nil
+    }
+    variables := []*ast.Identifier{{Token: p.curToken, Value: p.curToken.Literal}}
+
+    if p.peepTokenIs(token.COMMA) {
+        p.nextToken()
+
+        if !p.expectPeep(token.IDENT) {
+            return nil, nil, nil
+        }
+        variables = append(variables, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+    }
+
+    if !p.expectPeep(token.IN) {
+        return nil, nil, nil
+    }
+
+    p.nextToken()
+    iterable := p.parseExpression(LOWEST)
+
+    var condition ast.Expression
+    if p.peepTokenIs(token.IF) {
+        p.nextToken()
+        p.nextToken()
+        condition = p.parseExpression(LOWEST)
+    }
+
+    return variables, iterable, condition
+}
+
+func (p *Parser) parseSpreadExpression() ast.Expression {
+    exp := &ast.SpreadExpression{Token: p.curToken}
+
+    p.nextToken()
+    exp.Value = p.parseExpression(LOWEST)
+
+    return exp
 }
 
 func (p *Parser) parseStringLiteral() ast.Expression {
@@ -174,25 +417,43 @@ func (p *Parser) parseStringLiteral() ast.Expression {
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
     exp := &ast.CallExpression{Token: p.curToken, Function: function}
-    exp.Arguments = p.parseExpressionList(token.RIGHTPAREN)
+    exp.Arguments = p.parseCallArguments()
     return exp
 }
 
 func (p *Parser) parseCallArguments() []ast.Expression {
+    defer p.restoreArrows(p.allowArrows())
+
     args := []ast.Expression{}
+    seen := make(map[string]bool)
 
     if p.peepTokenIs(token.RIGHTPAREN) {
         p.nextToken()
         return args
     }
 
-    p.nextToken()
-    args = append(args, p.parseExpression(LOWEST))
-
-    for p.peepTokenIs(token.COMMA) {
+    for {
         p.nextToken()
+
+        if p.curTokenIs(token.IDENT) && p.peepTokenIs(token.COLON) {
+            arg := p.parseKeywordArgument()
+            if seen[arg.Name.Value] {
+                msg := fmt.Sprintf("duplicate keyword argument %s", arg.Name.Value)
+                p.errors = append(p.errors, msg)
+            }
+            seen[arg.Name.Value] = true
+            args = append(args, arg)
+        } else {
+            if len(seen) > 0 {
+                p.errors = append(p.errors, "positional argument follows keyword argument")
+            }
+            args = append(args, p.parseExpression(LOWEST))
+        }
+
+        if !p.peepTokenIs(token.COMMA) {
+            break
+        }
         p.nextToken()
-        args = append(args, p.parseExpression(LOWEST))
     }
 
     if !p.expectPeep(token.RIGHTPAREN) {
@@ -202,7 +463,28 @@ func (p *Parser) parseCallArguments() []ast.Expression {
     return args
 }
 
+func (p *Parser) parseKeywordArgument() *ast.KeywordArgument {
+    arg := &ast.KeywordArgument{Token: p.curToken}
+    arg.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+    p.nextToken()
+    p.nextToken()
+    arg.Value = p.parseExpression(LOWEST)
+
+    return arg
+}
+
 func (p *Parser) parseGroupedExpression() ast.Expression {
+    if !p.noArrow && p.isArrowParameterList() {
+        params, defaults, rest := p.parseFunctionParameters()
+        if !p.expectPeep(token.ARROW) {
+            return nil
+        }
+        return p.parseArrowFunction(params, defaults, rest)
+    }
+
+    defer p.restoreArrows(p.allowArrows())
+
     p.nextToken()
 
     exp := p.parseExpression(LOWEST)
@@ -215,7 +497,66 @@ func (p *Parser) parseGroupedExpression() ast.Expression {
 }
 
 func (p *Parser) parseIdentifier() ast.Expression {
-    return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+    ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+    if !p.noArrow && p.peepTokenIs(token.ARROW) {
+        p.nextToken()
+        return p.parseArrowFunction([]*ast.Identifier{ident}, []ast.Expression{nil}, nil)
+    }
+
+    return ident
+}
+
+// isArrowParameterList looks past the parenthesis at curToken to its match and
+// reports whether => follows, which makes it an arrow function's parameters
+// rather than a grouped expression. It scans a copy of the lexer so no tokens
+// are consumed.
+func (p *Parser) isArrowParameterList() bool {
+    lookahead := p.l.Copy()
+    tok := p.peepToken
+    depth := 1
+
+    for tok.Type != token.EOF {
+        switch tok.Type {
+        case token.LEFTPAREN:
+            depth++
+        case token.RIGHTPAREN:
+            depth--
+            if depth == 0 {
+                return lookahead.NextToken().Type == token.ARROW
+            }
+        }
+
+        tok = lookahead.NextToken()
+    }
+
+    return false
+}
+
+// parseArrowFunction parses the body after the => at curToken. An expression
+// body becomes a block holding that one expression, so its value is returned.
+func (p *Parser) parseArrowFunction(params []*ast.Identifier, defaults []ast.Expression, rest *ast.Identifier) ast.Expression {
+    lit := &ast.FunctionLiteral{Token: p.curToken, Parameters: params, Defaults: defaults, Rest: rest}
+
+    if p.peepTokenIs(token.LEFTBRACE) {
+        p.nextToken()
+        lit.Body = p.parseScopedBlockStatement()
+        return lit
+    }
+
+    p.nextToken()
+    bodyToken := p.curToken
+
+    p.pushConstScope()
+    body := p.parseExpression(LOWEST)
+    p.popConstScope()
+
+    lit.Body = &ast.BlockStatement{
+        Token:      bodyToken,
+        Statements: []ast.Statement{&ast.ExpressionStatement{Token: bodyToken, Expression: body}},
+    }
+
+    return lit
 }
 
 func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
@@ -267,24 +608,46 @@ func (p *Parser) ParseProgram() *ast.Program {
 }
 
 func (p *Parser) parseStatement() ast.Statement {
+    defer p.untrace(p.trace("parseStatement"))
+
     switch p.curToken.Type {
-    case token.LET:
+    case token.LET, token.CONST:
         return p.parseLetStatement()
     case token.RETURN:
         return p.parseReturnStatement()
+    case token.THROW:
+        return p.parseThrowStatement()
+    case token.DEFER:
+        return p.parseDeferStatement()
+    case token.IMPORT:
+        return p.parseImportStatement()
+    case token.EXPORT:
+        return p.parseExportStatement()
+    case token.FUNCTION:
+        if p.peepTokenIs(token.IDENT) {
+            return p.parseFunctionStatement()
+        }
+        return p.parseExpressionStatement()
     default:
         return p.parseExpressionStatement()
     }
 }
 
 func (p *Parser) parseLetStatement() *ast.LetStatement {
+    defer p.untrace(p.trace("parseLetStatement"))
+
     stmt := &ast.LetStatement{Token: p.curToken}
 
+    if p.peepTokenIs(token.LEFTBRACKET) || p.peepTokenIs(token.LEFTBRACE) {
+        return p.parseDestructuringLetStatement(stmt)
+    }
+
     if !p.expectPeep(token.IDENT) {
         return nil
     }
 
     stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+    p.declareName(stmt.Name.Value, stmt.IsConst())
 
     if !p.expectPeep(token.ASSIGN) {
         return nil
@@ -294,6 +657,68 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 
     stmt.Value = p.parseExpression(LOWEST)
 
+    if fl, ok := stmt.Value.(*ast.FunctionLiteral); ok {
+        fl.Name = stmt.Name.Value
+    }
+
+    if p.peepTokenIs(token.SEMICOLON) {
+        p.nextToken()
+    }
+
+    return stmt
+}
+
+func (p *Parser) parseDestructuringLetStatement(stmt *ast.LetStatement) *ast.LetStatement {
+    p.nextToken()
+
+    stmt.Pattern = p.parsePattern()
+    if stmt.Pattern == nil {
+        return nil
+    }
+
+    for _, name := range ast.PatternNames(stmt.Pattern) {
+        p.declareName(name, stmt.IsConst())
+    }
+
+    if !p.expectPeep(token.ASSIGN) {
+        return nil
+    }
+
+    p.nextToken()
+
+    stmt.Value = p.parseExpression(LOWEST)
+
+    if p.peepTokenIs(token.SEMICOLON) {
+        p.nextToken()
+    }
+
+    return stmt
+}
+
+func (p *Parser) parseFunctionStatement() *ast.FunctionStatement {
+    defer p.untrace(p.trace("parseFunctionStatement"))
+
+    stmt := &ast.FunctionStatement{Token: p.curToken}
+
+    p.nextToken()
+    stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+    p.declareName(stmt.Name.Value, false)
+
+    lit := &ast.FunctionLiteral{Token: stmt.Token, Name: stmt.Name.Value}
+
+    if !p.expectPeep(token.LEFTPAREN) {
+        return nil
+    }
+
+    lit.Parameters, lit.Defaults, lit.Rest = p.parseFunctionParameters()
+
+    if !p.expectPeep(token.LEFTBRACE) {
+        return nil
+    }
+
+    lit.Body = p.parseScopedBlockStatement()
+    stmt.Function = lit
+
     if p.peepTokenIs(token.SEMICOLON) {
         p.nextToken()
     }
@@ -302,6 +727,8 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 }
 
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
+    defer p.untrace(p.trace("parseReturnStatement"))
+
     stmt := &ast.ReturnStatement{Token: p.curToken}
 
     p.nextToken()
@@ -315,7 +742,96 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
     return stmt
 }
 
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+    defer p.untrace(p.trace("parseThrowStatement"))
+
+    stmt := &ast.ThrowStatement{Token: p.curToken}
+
+    p.nextToken()
+
+    stmt.Value = p.parseExpression(LOWEST)
+
+    if p.peepTokenIs(token.SEMICOLON) {
+        p.nextToken()
+    }
+
+    return stmt
+}
+
+func (p *Parser) parseImportStatement() ast.Statement {
+    defer p.untrace(p.trace("parseImportStatement"))
+
+    stmt := &ast.ImportStatement{Token: p.curToken}
+
+    if !p.expectPeep(token.STRING) {
+        return nil
+    }
+    stmt.Path = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+
+    if !p.expectPeep(token.AS) {
+        return nil
+    }
+
+    if !p.expectPeep(token.IDENT) {
+        return nil
+    }
+    stmt.Alias = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+    p.declareName(stmt.Alias.Value, false)
+
+    if p.peepTokenIs(token.SEMICOLON) {
+        p.nextToken()
+    }
+
+    return stmt
+}
+
+func (p *Parser) parseExportStatement() ast.Statement {
+    defer p.untrace(p.trace("parseExportStatement"))
+
+    stmt := &ast.ExportStatement{Token: p.curToken}
+    p.nextToken()
+
+    switch {
+    case p.curTokenIs(token.LET), p.curTokenIs(token.CONST):
+        let := p.parseLetStatement()
+        if let == nil {
+            return nil
+        }
+        stmt.Statement = let
+    case p.curTokenIs(token.FUNCTION) && p.peepTokenIs(token.IDENT):
+        fn := p.parseFunctionStatement()
+        if fn == nil {
+            return nil
+        }
+        stmt.Statement = fn
+    default:
+        msg := fmt.Sprintf("export must be followed by let, const or a named func, got %s instead", p.curToken.Type)
+        p.errors = append(p.errors, msg)
+        return nil
+    }
+
+    return stmt
+}
+
+func (p *Parser) parseDeferStatement() *ast.DeferStatement {
+    defer p.untrace(p.trace("parseDeferStatement"))
+
+    stmt := &ast.DeferStatement{Token: p.curToken}
+
+    p.nextToken()
+
+    stmt.Call = p.parseExpression(LOWEST)
+
+    if p.peepTokenIs(token.SEMICOLON) {
+        p.nextToken()
+    }
+
+    return stmt
+}
+
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
+    defer p.untrace(p.trace("parseExpressionStatement"))
+
     stmt := &ast.ExpressionStatement{Token: p.curToken}
 
     stmt.Expression = p.parseExpression(LOWEST)
@@ -328,15 +844,19 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 }
 
 func (p *Parser) parseExpression(precedence int) ast.Expression {
+    if p.tracer != nil {
+        defer p.untrace(p.trace("parseExpression " + precedenceNames[precedence]))
+    }
+
     prefix := p.prefixParseFns[p.curToken.Type]
     if prefix == nil {
         p.noPrefixParseFnError(p.curToken.Type)
         return nil
     }
 
-    leftExp := prefix()
+    leftExp := p.callPrefix(prefix)
 
-    for !p.peepTokenIs(token.SEMICOLON) && precedence < p.peepPrecedence() {
+    for !p.peepTokenIs(token.SEMICOLON) && p.continues(precedence) {
         infix := p.infixParseFns[p.peepToken.Type]
         if infix == nil {
             return leftExp
@@ -344,12 +864,28 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 
         p.nextToken()
 
-        leftExp = infix(leftExp)
+        leftExp = p.callInfix(infix, leftExp)
     }
 
     return leftExp
 }
 
+func (p *Parser) callPrefix(fn prefixParseFn) ast.Expression {
+    if p.tracer != nil {
+        defer p.untrace(p.trace(fnName(fn)))
+    }
+
+    return fn()
+}
+
+func (p *Parser) callInfix(fn infixParseFn, left ast.Expression) ast.Expression {
+    if p.tracer != nil {
+        defer p.untrace(p.trace(fnName(fn)))
+    }
+
+    return fn(left)
+}
+
 func (p *Parser) parseIntegerLiteral() ast.Expression {
     lit := &ast.IntegerLiteral{Token: p.curToken}
 
@@ -398,7 +934,61 @@ func (p *Parser) parseIfExpression() ast.Expression {
     return expression
 }
 
+func (p *Parser) parseTryExpression() ast.Expression {
+    expression := &ast.TryExpression{Token: p.curToken}
+
+    if !p.expectPeep(token.LEFTBRACE) {
+        return nil
+    }
+
+    expression.Block = p.parseBlockStatement()
+
+    if p.peepTokenIs(token.CATCH) {
+        p.nextToken()
+
+        if p.peepTokenIs(token.LEFTPAREN) {
+            p.nextToken()
+
+            if !p.expectPeep(token.IDENT) {
+                return nil
+            }
+
+            expression.Param = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+            if !p.expectPeep(token.RIGHTPAREN) {
+                return nil
+            }
+        }
+
+        if !p.expectPeep(token.LEFTBRACE) {
+            return nil
+        }
+
+        expression.Catch = p.parseScopedBlockStatement()
+    }
+
+    if p.peepTokenIs(token.FINALLY) {
+        p.nextToken()
+
+        if !p.expectPeep(token.LEFTBRACE) {
+            return nil
+        }
+
+        expression.Finally = p.parseBlockStatement()
+    }
+
+    if expression.Catch == nil && expression.Finally == nil {
+        p.errors = append(p.errors, "try requires a catch or finally block")
+        return nil
+    }
+
+    return expression
+}
+
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
+    defer p.untrace(p.trace("parseBlockStatement"))
+    defer p.restoreArrows(p.allowArrows())
+
     block := &ast.BlockStatement{Token: p.curToken}
     block.Statements = []ast.Statement{}
 
@@ -413,9 +1003,44 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
         p.nextToken()
     }
 
+    if p.curTokenIs(token.RIGHTBRACE) {
+        block.End = p.curToken
+    }
+
     return block
 }
 
+// parseScopedBlockStatement parses a block that gets its own environment at
+// runtime, such as a function body, so consts declared outside it may be
+// shadowed inside it.
+func (p *Parser) parseScopedBlockStatement() *ast.BlockStatement {
+    p.pushConstScope()
+    defer p.popConstScope()
+
+    return p.parseBlockStatement()
+}
+
+func (p *Parser) pushConstScope() {
+    p.constScopes = append(p.constScopes, make(map[string]bool))
+}
+
+func (p *Parser) popConstScope() {
+    p.constScopes = p.constScopes[:len(p.constScopes) - 1]
+}
+
+func (p *Parser) declareName(name string, constant bool) {
+    scope := p.constScopes[len(p.constScopes) - 1]
+
+    if scope[name] {
+        msg := fmt.Sprintf("cannot reassign constant %s", name)
+        p.errors = append(p.errors, msg)
+    }
+
+    if constant {
+        scope[name] = true
+    }
+}
+
 func (p *Parser) parseFunctionLiteral() ast.Expression {
     lit := &ast.FunctionLiteral{Token: p.curToken}
 
@@ -423,42 +1048,108 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
         return nil
     }
 
-    lit.Parameters = p.parseFunctionParameters()
+    lit.Parameters, lit.Defaults, lit.Rest = p.parseFunctionParameters()
+
+    if !p.expectPeep(token.LEFTBRACE) {
+        return nil
+    }
+
+    lit.Body = p.parseScopedBlockStatement()
+
+    return lit
+}
+
+func (p *Parser) parseMacroLiteral() ast.Expression {
+    lit := &ast.MacroLiteral{Token: p.curToken}
+
+    if !p.expectPeep(token.LEFTPAREN) {
+        return nil
+    }
+
+    params, defaults, rest := p.parseFunctionParameters()
+    if rest != nil || hasDefault(defaults) {
+        p.errors = append(p.errors, "macro parameters cannot have defaults or a rest parameter")
+        return nil
+    }
+    lit.Parameters = params
 
     if !p.expectPeep(token.LEFTBRACE) {
         return nil
     }
 
-    lit.Body = p.parseBlockStatement()
+    lit.Body = p.parseScopedBlockStatement()
 
     return lit
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
+func hasDefault(defaults []ast.Expression) bool {
+    for _, d := range defaults {
+        if d != nil {
+            return true
+        }
+    }
+
+    return false
+}
+
+func (p *Parser) parseFunctionParameters() ([]*ast.Identifier, []ast.Expression, *ast.Identifier) {
     identifiers := []*ast.Identifier{}
+    defaults := []ast.Expression{}
+    var rest *ast.Identifier
 
     if p.peepTokenIs(token.RIGHTPAREN) {
         p.nextToken()
-        return identifiers
+        return identifiers, defaults, rest
     }
 
-    p.nextToken()
+    for {
+        p.nextToken()
 
-    ident := &ast.Identifier{ Token: p.curToken, Value: p.curToken.Literal }
-    identifiers = append(identifiers, ident)
+        if rest != nil {
+            p.errors = append(p.errors, "rest parameter must be the last parameter")
+            return nil, nil, nil
+        }
 
-    for p.peepTokenIs(token.COMMA) {
-        p.nextToken()
+        if p.curTokenIs(token.ELLIPSIS) {
+            if !p.expectPeep(token.IDENT) {
+                return nil, nil, nil
+            }
+            rest = &ast.Identifier{ Token: p.curToken, Value: p.curToken.Literal }
+        } else {
+            if !p.curTokenIs(token.IDENT) {
+                msg := fmt.Sprintf("expected parameter name, got %s instead", p.curToken.Type)
+                p.errors = append(p.errors, msg)
+                return nil, nil, nil
+            }
+
+            ident := &ast.Identifier{ Token: p.curToken, Value: p.curToken.Literal }
+            var def ast.Expression
+
+            if p.peepTokenIs(token.ASSIGN) {
+                p.nextToken()
+                p.nextToken()
+                def = p.parseExpression(LOWEST)
+            } else if len(defaults) > 0 && defaults[len(defaults) - 1] != nil {
+                msg := fmt.Sprintf("parameter %s without a default follows a parameter with a default", ident.Value)
+                p.errors = append(p.errors, msg)
+                return nil, nil, nil
+            }
+
+            identifiers = append(identifiers, ident)
+            defaults = append(defaults, def)
+        }
+
+        if !p.peepTokenIs(token.COMMA) {
+            break
+        }
         p.nextToken()
-        ident := &ast.Identifier{ Token: p.curToken, Value: p.curToken.Literal }
-        identifiers = append(identifiers, ident)
     }
 
     if !p.expectPeep(token.RIGHTPAREN) {
-        return nil
+        return nil, nil, nil
     }
 
-    return identifiers
+    return identifiers, defaults, rest
 }
 
 func (p *Parser) curTokenIs(t token.TokenType) bool {
@@ -508,3 +1199,200 @@ func (p *Parser) noPrefixParseFnError(t token.TokenType) {
     msg := fmt.Sprintf("no prefix parse function for %s found", t)
     p.errors = append(p.errors, msg)
 }
+
+func (p *Parser) parseMatchExpression() ast.Expression {
+    defer p.restoreArrows(p.allowArrows())
+
+    exp := &ast.MatchExpression{Token: p.curToken}
+
+    if !p.expectPeep(token.LEFTPAREN) {
+        return nil
+    }
+
+    p.nextToken()
+    exp.Subject = p.parseExpression(LOWEST)
+
+    if !p.expectPeep(token.RIGHTPAREN) {
+        return nil
+    }
+
+    if !p.expectPeep(token.LEFTBRACE) {
+        return nil
+    }
+
+    for !p.peepTokenIs(token.RIGHTBRACE) {
+        p.nextToken()
+
+        arm := &ast.MatchArm{Token: p.curToken}
+        arm.Pattern = p.parsePattern()
+        if arm.Pattern == nil {
+            return nil
+        }
+
+        if p.peepTokenIs(token.IF) {
+            p.nextToken()
+            p.nextToken()
+            noArrow := p.noArrow
+            p.noArrow = true
+            arm.Guard = p.parseExpression(LOWEST)
+            p.noArrow = noArrow
+        }
+
+        if !p.expectPeep(token.ARROW) {
+            return nil
+        }
+
+        p.nextToken()
+        arm.Body = p.parseExpression(LOWEST)
+
+        exp.Arms = append(exp.Arms, arm)
+
+        if !p.peepTokenIs(token.RIGHTBRACE) && !p.expectPeep(token.COMMA) {
+            return nil
+        }
+    }
+
+    if !p.expectPeep(token.RIGHTBRACE) {
+        return nil
+    }
+    exp.End = p.curToken
+
+    return exp
+}
+
+// allowArrows turns arrow functions back on for the inside of a bracket or
+// brace, where => can no longer end an enclosing match guard. It returns the
+// previous setting for restoreArrows.
+func (p *Parser) allowArrows() bool {
+    noArrow := p.noArrow
+    p.noArrow = false
+    return noArrow
+}
+
+func (p *Parser) restoreArrows(noArrow bool) {
+    p.noArrow = noArrow
+}
+
+func (p *Parser) parsePattern() ast.Expression {
+    switch p.curToken.Type {
+    case token.IDENT:
+        return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+    case token.LEFTBRACKET:
+        return p.parseArrayPattern()
+    case token.LEFTBRACE:
+        return p.parseHashPattern()
+    case token.INT, token.STRING, token.TRUE, token.FALSE, token.MINUS:
+        return p.parseExpression(PREFIX)
+    default:
+        msg := fmt.Sprintf("expected pattern, got %s instead", p.curToken.Type)
+        p.errors = append(p.errors, msg)
+        return nil
+    }
+}
+
+func (p *Parser) parseArrayPattern() ast.Expression {
+    pattern := &ast.ArrayPattern{Token: p.curToken}
+
+    for !p.peepTokenIs(token.RIGHTBRACKET) {
+        p.nextToken()
+
+        if pattern.Rest != nil {
+            p.errors = append(p.errors, "rest element must be the last element of a pattern")
+            return nil
+        }
+
+        if p.curTokenIs(token.ELLIPSIS) {
+            if !p.expectPeep(token.IDENT) {
+                return nil
+            }
+            pattern.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+        } else {
+            element := p.parsePatternWithDefault()
+            if element == nil {
+                return nil
+            }
+            pattern.Elements = append(pattern.Elements, element)
+        }
+
+        if !p.peepTokenIs(token.RIGHTBRACKET) && !p.expectPeep(token.COMMA) {
+            return nil
+        }
+    }
+
+    if !p.expectPeep(token.RIGHTBRACKET) {
+        return nil
+    }
+
+    return pattern
+}
+
+func (p *Parser) parseHashPattern() ast.Expression {
+    pattern := &ast.HashPattern{Token: p.curToken}
+
+    for !p.peepTokenIs(token.RIGHTBRACE) {
+        p.nextToken()
+
+        if !p.curTokenIs(token.IDENT) && !p.curTokenIs(token.STRING) {
+            msg := fmt.Sprintf("expected hash pattern key, got %s instead", p.curToken.Type)
+            p.errors = append(p.errors, msg)
+            return nil
+        }
+
+        key := p.curToken
+        var value ast.Expression
+
+        if p.peepTokenIs(token.COLON) {
+            p.nextToken()
+            p.nextToken()
+            value = p.parsePatternWithDefault()
+            if value == nil {
+                return nil
+            }
+        } else if key.Type == token.IDENT {
+            value = &ast.Identifier{Token: key, Value: key.Literal}
+            if p.peepTokenIs(token.ASSIGN) {
+                value = p.parseDefaultPattern(value)
+            }
+        } else {
+            msg := fmt.Sprintf("expected : after hash pattern key %q", key.Literal)
+            p.errors = append(p.errors, msg)
+            return nil
+        }
+
+        pattern.Keys = append(pattern.Keys, key.Literal)
+        pattern.Values = append(pattern.Values, value)
+
+        if !p.peepTokenIs(token.RIGHTBRACE) && !p.expectPeep(token.COMMA) {
+            return nil
+        }
+    }
+
+    if !p.expectPeep(token.RIGHTBRACE) {
+        return nil
+    }
+
+    return pattern
+}
+
+func (p *Parser) parsePatternWithDefault() ast.Expression {
+    pattern := p.parsePattern()
+    if pattern == nil {
+        return nil
+    }
+
+    if p.peepTokenIs(token.ASSIGN) {
+        return p.parseDefaultPattern(pattern)
+    }
+
+    return pattern
+}
+
+func (p *Parser) parseDefaultPattern(pattern ast.Expression) ast.Expression {
+    p.nextToken()
+    dp := &ast.DefaultPattern{Token: p.curToken, Pattern: pattern}
+
+    p.nextToken()
+    dp.Default = p.parseExpression(LOWEST)
+
+    return dp
+}
@@ -2,6 +2,8 @@ package parser
 
 import (
 	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 	"github.com/JakeNorman007/interpreter/ast"
 	"github.com/JakeNorman007/interpreter/lexer"
@@ -506,7 +508,8 @@ func ParsingHashLiteralStringKeys(t *testing.T) {
         "three": 3,
     }
 
-    for key, value := range hash.Pairs {
+    for _, pair := range hash.Pairs {
+        key, value := pair.Key, pair.Value
         literal, ok := key.(*ast.StringLiteral)
         if !ok {
             t.Errorf("key is not ast.StringLiteral, got=%T", key)
@@ -567,7 +570,8 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
         },
     }
 
-    for key, value := range hash.Pairs {
+    for _, pair := range hash.Pairs {
+        key, value := pair.Key, pair.Value
         literal, ok := key.(*ast.StringLiteral)
         if !ok {
             t.Errorf("key is not ast.StringLiteral, got=%T", key)
@@ -582,6 +586,14 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 
         testFunc(value)
     }
+
+    keys := []string{}
+    for _, pair := range hash.Pairs {
+        keys = append(keys, pair.Key.String())
+    }
+    if strings.Join(keys, " ") != "one two three" {
+        t.Errorf("hash.Pairs not in source order, got=%v", keys)
+    }
 }
 
 func testLiteralExpression(
@@ -900,3 +912,830 @@ func TestStringLiteral(t *testing.T) {
         t.Errorf("literal.Value is not %q, got=%q", "Hello World", literal.Value)
     }
 }
+
+func TestFunctionStatementParsing(t *testing.T) {
+    input := "func add(x, y) { x + y; }"
+
+    l := lexer.New(input)
+    p := New(l)
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    if len(program.Statements) != 1 {
+        t.Fatalf("program.Statements does not contain 1 statement, got=%d", len(program.Statements))
+    }
+
+    stmt, ok := program.Statements[0].(*ast.FunctionStatement)
+    if !ok {
+        t.Fatalf("program.Statements[0] is not ast.FunctionStatement, got=%T", program.Statements[0])
+    }
+
+    if stmt.Name.Value != "add" {
+        t.Errorf("stmt.Name.Value is not 'add', got=%q", stmt.Name.Value)
+    }
+
+    if stmt.Function.Name != "add" {
+        t.Errorf("stmt.Function.Name is not 'add', got=%q", stmt.Function.Name)
+    }
+
+    if len(stmt.Function.Parameters) != 2 {
+        t.Fatalf("function parameters wrong, wanted 2, got=%d", len(stmt.Function.Parameters))
+    }
+
+    testLiteralExpression(t, stmt.Function.Parameters[0], "x")
+    testLiteralExpression(t, stmt.Function.Parameters[1], "y")
+
+    bodyStmt := stmt.Function.Body.Statements[0].(*ast.ExpressionStatement)
+    testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
+}
+
+func TestLetStatementNamesFunctionLiteral(t *testing.T) {
+    input := "let add = func(x, y) { x + y; };"
+
+    l := lexer.New(input)
+    p := New(l)
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    stmt := program.Statements[0].(*ast.LetStatement)
+    function, ok := stmt.Value.(*ast.FunctionLiteral)
+    if !ok {
+        t.Fatalf("stmt.Value is not ast.FunctionLiteral, got=%T", stmt.Value)
+    }
+
+    if function.Name != "add" {
+        t.Errorf("function.Name is not 'add', got=%q", function.Name)
+    }
+}
+
+func TestFunctionDefaultAndRestParameterParsing(t *testing.T) {
+    input := "func(a, b = 2, ...rest) { a; }"
+
+    l := lexer.New(input)
+    p := New(l)
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    stmt := program.Statements[0].(*ast.ExpressionStatement)
+    function := stmt.Expression.(*ast.FunctionLiteral)
+
+    if len(function.Parameters) != 2 {
+        t.Fatalf("function literal parameters wrong, wanted 2, got=%d", len(function.Parameters))
+    }
+
+    testLiteralExpression(t, function.Parameters[0], "a")
+    testLiteralExpression(t, function.Parameters[1], "b")
+
+    if function.Defaults[0] != nil {
+        t.Errorf("function.Defaults[0] is not nil, got=%s", function.Defaults[0])
+    }
+
+    testIntegerLiteral(t, function.Defaults[1], 2)
+
+    if function.Rest == nil || function.Rest.Value != "rest" {
+        t.Fatalf("function.Rest is not 'rest', got=%v", function.Rest)
+    }
+
+    if function.String() != "func(a, b = 2, ...rest) a" {
+        t.Errorf("function.String() is wrong, got=%q", function.String())
+    }
+}
+
+func TestFunctionParameterErrors(t *testing.T) {
+    tests := []struct {
+        input       string
+        expected    string
+    }{
+        {"func(...rest, a) {}", "rest parameter must be the last parameter"},
+        {"func(a = 1, b) {}", "parameter b without a default follows a parameter with a default"},
+        {"func(1) {}", "expected parameter name, got INT instead"},
+    }
+
+    for _, tt := range tests {
+        l := lexer.New(tt.input)
+        p := New(l)
+        p.ParseProgram()
+
+        errors := p.Errors()
+        if len(errors) == 0 {
+            t.Errorf("expected parser errors for %q, got none", tt.input)
+            continue
+        }
+
+        if errors[0] != tt.expected {
+            t.Errorf("wrong parser error, expected=%q, got=%q", tt.expected, errors[0])
+        }
+    }
+}
+
+func TestSpreadArgumentParsing(t *testing.T) {
+    input := "add(1, ...xs);"
+
+    l := lexer.New(input)
+    p := New(l)
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    stmt := program.Statements[0].(*ast.ExpressionStatement)
+    exp := stmt.Expression.(*ast.CallExpression)
+
+    if len(exp.Arguments) != 2 {
+        t.Fatalf("wrong length of arguments, got=%d", len(exp.Arguments))
+    }
+
+    spread, ok := exp.Arguments[1].(*ast.SpreadExpression)
+    if !ok {
+        t.Fatalf("exp.Arguments[1] is not ast.SpreadExpression, got=%T", exp.Arguments[1])
+    }
+
+    testIdentifier(t, spread.Value, "xs")
+}
+
+func TestKeywordArgumentParsing(t *testing.T) {
+    input := "log(msg, verbose: true, level: 1 + 2);"
+
+    l := lexer.New(input)
+    p := New(l)
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    stmt := program.Statements[0].(*ast.ExpressionStatement)
+    exp := stmt.Expression.(*ast.CallExpression)
+
+    if len(exp.Arguments) != 3 {
+        t.Fatalf("wrong length of arguments, got=%d", len(exp.Arguments))
+    }
+
+    testIdentifier(t, exp.Arguments[0], "msg")
+
+    verbose, ok := exp.Arguments[1].(*ast.KeywordArgument)
+    if !ok {
+        t.Fatalf("exp.Arguments[1] is not ast.KeywordArgument, got=%T", exp.Arguments[1])
+    }
+
+    if verbose.Name.Value != "verbose" {
+        t.Errorf("verbose.Name.Value is not 'verbose', got=%q", verbose.Name.Value)
+    }
+    testBooleanLiteral(t, verbose.Value, true)
+
+    level := exp.Arguments[2].(*ast.KeywordArgument)
+    testInfixExpression(t, level.Value, 1, "+", 2)
+
+    if exp.String() != "log(msg, verbose: true, level: (1 + 2))" {
+        t.Errorf("exp.String() is wrong, got=%q", exp.String())
+    }
+}
+
+func TestKeywordArgumentErrors(t *testing.T) {
+    tests := []struct {
+        input       string
+        expected    string
+    }{
+        {"f(a: 1, a: 2)", "duplicate keyword argument a"},
+        {"f(a: 1, 2)", "positional argument follows keyword argument"},
+    }
+
+    for _, tt := range tests {
+        l := lexer.New(tt.input)
+        p := New(l)
+        p.ParseProgram()
+
+        errors := p.Errors()
+        if len(errors) == 0 {
+            t.Errorf("expected parser errors for %q, got none", tt.input)
+            continue
+        }
+
+        if errors[0] != tt.expected {
+            t.Errorf("wrong parser error, expected=%q, got=%q", tt.expected, errors[0])
+        }
+    }
+}
+
+func TestParsingSliceExpressions(t *testing.T) {
+    tests := []struct {
+        input       string
+        expected    string
+    }{
+        {"xs[1:3]", "(xs[1:3])"},
+        {"xs[:2]", "(xs[:2])"},
+        {"xs[2:]", "(xs[2:])"},
+        {"xs[:]", "(xs[:])"},
+        {"xs[::2]", "(xs[::2])"},
+        {"xs[1:5:2]", "(xs[1:5:2])"},
+        {"xs[a + 1:-1]", "(xs[(a + 1):(-1)])"},
+    }
+
+    for _, tt := range tests {
+        l := lexer.New(tt.input)
+        p := New(l)
+        program := p.ParseProgram()
+        checkParserErrors(t, p)
+
+        stmt := program.Statements[0].(*ast.ExpressionStatement)
+        if _, ok := stmt.Expression.(*ast.SliceExpression); !ok {
+            t.Fatalf("exp is not ast.SliceExpression, got=%T", stmt.Expression)
+        }
+
+        if program.String() != tt.expected {
+            t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+        }
+    }
+}
+
+func TestParsingPropertyExpressions(t *testing.T) {
+    tests := []struct {
+        input       string
+        expected    string
+    }{
+        {"person.name", "(person.name)"},
+        {"a.b.c", "((a.b).c)"},
+        {"-a.b", "(-(a.b))"},
+        {"a.b + c.d", "((a.b) + (c.d))"},
+        {"a.b(1, 2)", "(a.b)(1, 2)"},
+        {"a.b[0]", "((a.b)[0])"},
+    }
+
+    for _, tt := range tests {
+        l := lexer.New(tt.input)
+        p := New(l)
+        program := p.ParseProgram()
+        checkParserErrors(t, p)
+
+        if program.String() != tt.expected {
+            t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+        }
+    }
+}
+
+func TestParsingMatchExpressions(t *testing.T) {
+    input := `match (x) {
+        0 => "zero",
+        -1 => "minus one",
+        [first, ...rest] => first,
+        {name, "age": a} if a > 18 => name,
+        _ => "other",
+    }`
+
+    l := lexer.New(input)
+    p := New(l)
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    stmt := program.Statements[0].(*ast.ExpressionStatement)
+    exp, ok := stmt.Expression.(*ast.MatchExpression)
+    if !ok {
+        t.Fatalf("exp is not ast.MatchExpression, got=%T", stmt.Expression)
+    }
+
+    testIdentifier(t, exp.Subject, "x")
+
+    if len(exp.Arms) != 5 {
+        t.Fatalf("exp.Arms does not contain 5 arms, got=%d", len(exp.Arms))
+    }
+
+    testIntegerLiteral(t, exp.Arms[0].Pattern, 0)
+
+    array, ok := exp.Arms[2].Pattern.(*ast.ArrayPattern)
+    if !ok {
+        t.Fatalf("exp.Arms[2].Pattern is not ast.ArrayPattern, got=%T", exp.Arms[2].Pattern)
+    }
+    if len(array.Elements) != 1 || array.Rest == nil || array.Rest.Value != "rest" {
+        t.Errorf("array pattern is wrong, got=%s", array)
+    }
+
+    hash, ok := exp.Arms[3].Pattern.(*ast.HashPattern)
+    if !ok {
+        t.Fatalf("exp.Arms[3].Pattern is not ast.HashPattern, got=%T", exp.Arms[3].Pattern)
+    }
+    if len(hash.Keys) != 2 || hash.Keys[0] != "name" || hash.Keys[1] != "age" {
+        t.Errorf("hash pattern keys are wrong, got=%v", hash.Keys)
+    }
+    testInfixExpression(t, exp.Arms[3].Guard, "a", ">", 18)
+
+    testIdentifier(t, exp.Arms[4].Pattern, "_")
+
+    expected := "match (x) { 0 => zero, (-1) => minus one, [first, ...rest] => first, " +
+        "{name, age: a} if (a > 18) => name, _ => other }"
+    if exp.String() != expected {
+        t.Errorf("exp.String() is wrong, expected=%q, got=%q", expected, exp.String())
+    }
+}
+
+func TestDestructuringLetStatements(t *testing.T) {
+    tests := []struct {
+        input       string
+        expected    string
+    }{
+        {"let [a, b, ...rest] = arr;", "let [a, b, ...rest] = arr;"},
+        {"let {name, age} = person;", "let {name, age} = person;"},
+        {"let {name: n, \"age\": a} = person;", "let {name: n, age: a} = person;"},
+        {"let [a, [b, c]] = arr;", "let [a, [b, c]] = arr;"},
+        {"let [a, b = 2] = arr;", "let [a, b = 2] = arr;"},
+        {"let {name, age = 1 + 1} = person;", "let {name, age = (1 + 1)} = person;"},
+        {"let {pos: [x, y]} = point;", "let {pos: [x, y]} = point;"},
+    }
+
+    for _, tt := range tests {
+        l := lexer.New(tt.input)
+        p := New(l)
+        program := p.ParseProgram()
+        checkParserErrors(t, p)
+
+        stmt, ok := program.Statements[0].(*ast.LetStatement)
+        if !ok {
+            t.Fatalf("program.Statements[0] is not ast.LetStatement, got=%T", program.Statements[0])
+        }
+
+        if stmt.Pattern == nil {
+            t.Fatalf("stmt.Pattern is nil for %q", tt.input)
+        }
+
+        if program.String() != tt.expected {
+            t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+        }
+    }
+}
+
+func TestTryExpressionParsing(t *testing.T) {
+    input := `try { risky(); } catch (e) { e; } finally { cleanup(); }`
+
+    l := lexer.New(input)
+    p := New(l)
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    stmt := program.Statements[0].(*ast.ExpressionStatement)
+    exp, ok := stmt.Expression.(*ast.TryExpression)
+    if !ok {
+        t.Fatalf("exp is not ast.TryExpression, got=%T", stmt.Expression)
+    }
+
+    if len(exp.Block.Statements) != 1 {
+        t.Errorf("exp.Block does not contain 1 statement, got=%d", len(exp.Block.Statements))
+    }
+
+    if exp.Param == nil || exp.Param.Value != "e" {
+        t.Fatalf("exp.Param is not 'e', got=%v", exp.Param)
+    }
+
+    if exp.Catch == nil || exp.Finally == nil {
+        t.Fatalf("exp.Catch or exp.Finally is nil")
+    }
+
+    if exp.String() != "try risky() catch(e) e finally cleanup()" {
+        t.Errorf("exp.String() is wrong, got=%q", exp.String())
+    }
+}
+
+func TestThrowStatementParsing(t *testing.T) {
+    input := `throw "boom";`
+
+    l := lexer.New(input)
+    p := New(l)
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    stmt, ok := program.Statements[0].(*ast.ThrowStatement)
+    if !ok {
+        t.Fatalf("program.Statements[0] is not ast.ThrowStatement, got=%T", program.Statements[0])
+    }
+
+    str, ok := stmt.Value.(*ast.StringLiteral)
+    if !ok || str.Value != "boom" {
+        t.Errorf("stmt.Value is not \"boom\", got=%s", stmt.Value)
+    }
+}
+
+func TestTryWithoutHandlerError(t *testing.T) {
+    l := lexer.New("try { 1 }")
+    p := New(l)
+    p.ParseProgram()
+
+    errors := p.Errors()
+    if len(errors) != 1 || errors[0] != "try requires a catch or finally block" {
+        t.Errorf("wrong parser errors, got=%v", errors)
+    }
+}
+
+func TestDeferStatementParsing(t *testing.T) {
+    input := `defer close(file);`
+
+    l := lexer.New(input)
+    p := New(l)
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    stmt, ok := program.Statements[0].(*ast.DeferStatement)
+    if !ok {
+        t.Fatalf("program.Statements[0] is not ast.DeferStatement, got=%T", program.Statements[0])
+    }
+
+    if _, ok := stmt.Call.(*ast.CallExpression); !ok {
+        t.Errorf("stmt.Call is not ast.CallExpression, got=%T", stmt.Call)
+    }
+
+    if stmt.String() != "defer close(file);" {
+        t.Errorf("stmt.String() is wrong, got=%q", stmt.String())
+    }
+}
+
+func TestConstStatements(t *testing.T) {
+    input := "const x = 5;"
+
+    l := lexer.New(input)
+    p := New(l)
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    stmt, ok := program.Statements[0].(*ast.LetStatement)
+    if !ok {
+        t.Fatalf("program.Statements[0] is not ast.LetStatement, got=%T", program.Statements[0])
+    }
+
+    if !stmt.IsConst() {
+        t.Errorf("stmt.IsConst() is false for a const statement")
+    }
+
+    if stmt.String() != "const x = 5;" {
+        t.Errorf("stmt.String() is wrong, got=%q", stmt.String())
+    }
+}
+
+func TestConstReassignmentErrors(t *testing.T) {
+    tests := []struct {
+        input           string
+        expectedErrors  int
+    }{
+        {"const x = 1; let x = 2;", 1},
+        {"const x = 1; const x = 2;", 1},
+        {"const x = 1; func x() {}", 1},
+        {"const [a, b] = [1, 2]; let a = 3;", 1},
+        {"const x = 1; if (true) { let x = 2; }", 1},
+        {"let x = 1; let x = 2;", 0},
+        {"const x = 1; let f = func() { let x = 2; };", 0},
+        {"const x = 1; try { 1 } catch (e) { let x = 2; }", 0},
+    }
+
+    for _, tt := range tests {
+        l := lexer.New(tt.input)
+        p := New(l)
+        p.ParseProgram()
+
+        if len(p.Errors()) != tt.expectedErrors {
+            t.Errorf("wrong number of errors for %q, expected=%d, got=%v", tt.input, tt.expectedErrors, p.Errors())
+        }
+    }
+}
+
+func TestParsingOptionalOperators(t *testing.T) {
+    tests := []struct {
+        input       string
+        expected    string
+    }{
+        {"a ?? b", "(a ?? b)"},
+        {"a ?? b ?? c", "((a ?? b) ?? c)"},
+        {"a ?? b == c", "(a ?? (b == c))"},
+        {"a + b ?? c * d", "((a + b) ?? (c * d))"},
+        {"a?.b", "(a?.b)"},
+        {"a?.b.c", "((a?.b).c)"},
+        {"a?.[0]", "(a?.[0])"},
+        {"a?.[k]?.b", "((a?.[k])?.b)"},
+        {"a?.[1:2]", "(a?.[1:2])"},
+        {"a?.[:]?.b", "((a?.[:])?.b)"},
+        {"a?.b ?? 5", "((a?.b) ?? 5)"},
+        {"a?.b(1)", "(a?.b)(1)"},
+    }
+
+    for _, tt := range tests {
+        l := lexer.New(tt.input)
+        p := New(l)
+        program := p.ParseProgram()
+        checkParserErrors(t, p)
+
+        if program.String() != tt.expected {
+            t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+        }
+    }
+}
+
+func TestParsingPipelineExpressions(t *testing.T) {
+    tests := []struct {
+        input       string
+        expected    string
+    }{
+        {"x |> f(y)", "(x |> f(y))"},
+        {"x |> f", "(x |> f)"},
+        {"x |> f(1) |> g(2)", "((x |> f(1)) |> g(2))"},
+        {"a + b |> f()", "((a + b) |> f())"},
+        {"a ?? b |> f()", "((a ?? b) |> f())"},
+        {"xs |> m.sort()", "(xs |> (m.sort)())"},
+    }
+
+    for _, tt := range tests {
+        l := lexer.New(tt.input)
+        p := New(l)
+        program := p.ParseProgram()
+        checkParserErrors(t, p)
+
+        if program.String() != tt.expected {
+            t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+        }
+    }
+}
+
+func TestArrowFunctionParsing(t *testing.T) {
+    tests := []struct {
+        input           string
+        expectedParams  []string
+        expected        string
+    }{
+        {"x => x * 2", []string{"x"}, "(x) => (x * 2)"},
+        {"(x) => x * 2", []string{"x"}, "(x) => (x * 2)"},
+        {"(x, y) => x + y", []string{"x", "y"}, "(x, y) => (x + y)"},
+        {"() => 5", []string{}, "() => 5"},
+        {"(x, y = 2) => { let z = x; z + y }", []string{"x", "y"}, "(x, y = 2) => let z = x;(z + y)"},
+        {"(first, ...rest) => rest", []string{"first"}, "(first, ...rest) => rest"},
+        {"((x)) => x", nil, ""},
+    }
+
+    for _, tt := range tests {
+        l := lexer.New(tt.input)
+        p := New(l)
+        program := p.ParseProgram()
+
+        if tt.expectedParams == nil {
+            if len(p.Errors()) == 0 {
+                t.Errorf("expected parser errors for %q, got none", tt.input)
+            }
+            continue
+        }
+        checkParserErrors(t, p)
+
+        stmt := program.Statements[0].(*ast.ExpressionStatement)
+        function, ok := stmt.Expression.(*ast.FunctionLiteral)
+        if !ok {
+            t.Fatalf("stmt.Expression is not ast.FunctionLiteral, got=%T", stmt.Expression)
+        }
+
+        if len(function.Parameters) != len(tt.expectedParams) {
+            t.Fatalf("length parameters wrong, want %d, got=%d", len(tt.expectedParams), len(function.Parameters))
+        }
+
+        for i, ident := range tt.expectedParams {
+            testLiteralExpression(t, function.Parameters[i], ident)
+        }
+
+        if function.String() != tt.expected {
+            t.Errorf("function.String() is wrong, expected=%q, got=%q", tt.expected, function.String())
+        }
+    }
+}
+
+func TestArrowFunctionLookahead(t *testing.T) {
+    tests := []struct {
+        input       string
+        expected    string
+    }{
+        {"(a + b) * c", "((a + b) * c)"},
+        {"(a) + (b)", "(a + b)"},
+        {"f((x) => x, (1 + 2))", "f((x) => x, (1 + 2))"},
+        {"xs |> f(x => x * 2)", "(xs |> f((x) => (x * 2)))"},
+        {"match (n) { x if (x) => 1 }", "match (n) { x if x => 1 }"},
+        {"match (n) { x if ok => 1 }", "match (n) { x if ok => 1 }"},
+        {"match (n) { n if any(xs, (y) => y > n) => 1 }", "match (n) { n if any(xs, (y) => (y > n)) => 1 }"},
+        {"match (n) { n if [(y) => y][0](n) => 1 }", "match (n) { n if ([(y) => y][0])(n) => 1 }"},
+        {"match (n) { x if f({a: y => y}) => 1 }", "match (n) { x if f({a:(y) => y}) => 1 }"},
+        {"match (n) { x if match (x) { z if z => true } == (ok) => 1 }", "match (n) { x if (match (x) { z if z => true } == ok) => 1 }"},
+    }
+
+    for _, tt := range tests {
+        l := lexer.New(tt.input)
+        p := New(l)
+        program := p.ParseProgram()
+        checkParserErrors(t, p)
+
+        if program.String() != tt.expected {
+            t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+        }
+    }
+}
+
+func TestParsingRangeExpressions(t *testing.T) {
+    tests := []struct {
+        input       string
+        expected    string
+    }{
+        {"1..10", "(1..10)"},
+        {"0..<n", "(0..<n)"},
+        {"1..n + 1", "(1..(n + 1))"},
+        {"a * 2..b", "((a * 2)..b)"},
+        {"x in 1..10", "(x in (1..10))"},
+        {"(1..10)[2]", "((1..10)[2])"},
+    }
+
+    for _, tt := range tests {
+        l := lexer.New(tt.input)
+        p := New(l)
+        program := p.ParseProgram()
+        checkParserErrors(t, p)
+
+        if program.String() != tt.expected {
+            t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+        }
+    }
+}
+
+func TestParsingComprehensions(t *testing.T) {
+    tests := []struct {
+        input       string
+        expected    string
+    }{
+        {"[x * 2 for x in xs]", "[(x * 2) for x in xs]"},
+        {"[x for x in xs if x > 0]", "[x for x in xs if (x > 0)]"},
+        {"[i + x for i, x in 1..3]", "[(i + x) for i, x in (1..3)]"},
+        {"{k: v * 2 for k, v in h if v in ys}", "{k:(v * 2) for k, v in h if (v in ys)}"},
+        {"[[y for y in x] for x in xs]", "[[y for y in x] for x in xs]"},
+    }
+
+    for _, tt := range tests {
+        l := lexer.New(tt.input)
+        p := New(l)
+        program := p.ParseProgram()
+        checkParserErrors(t, p)
+
+        if program.String() != tt.expected {
+            t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+        }
+    }
+}
+
+func TestComprehensionParseErrors(t *testing.T) {
+    tests := []string{
+        "[x for in xs]",
+        "[x for x xs]",
+        "[x for x, in xs]",
+        "{k: v for k, v in h",
+    }
+
+    for _, input := range tests {
+        l := lexer.New(input)
+        p := New(l)
+        p.ParseProgram()
+
+        if len(p.Errors()) == 0 {
+            t.Errorf("expected parse errors for %q", input)
+        }
+    }
+}
+
+func TestParsingImportExport(t *testing.T) {
+    tests := []struct {
+        input       string
+        expected    string
+    }{
+        {`import "lib/math" as m;`, `import "lib/math" as m;`},
+        {`export let x = 1;`, `export let x = 1;`},
+        {`export const [a, b] = xs;`, `export const [a, b] = xs;`},
+    }
+
+    for _, tt := range tests {
+        l := lexer.New(tt.input)
+        p := New(l)
+        program := p.ParseProgram()
+        checkParserErrors(t, p)
+
+        if program.String() != tt.expected {
+            t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+        }
+    }
+
+    program := New(lexer.New(`export func area(r) { r * r }`)).ParseProgram()
+    stmt, ok := program.Statements[0].(*ast.ExportStatement)
+    if !ok {
+        t.Fatalf("statement is not *ast.ExportStatement. got=%T", program.Statements[0])
+    }
+    if names := stmt.Names(); len(names) != 1 || names[0] != "area" {
+        t.Errorf("wrong export names: %v", names)
+    }
+
+    for _, input := range []string{`import math as m;`, `import "math";`, `export x;`, `export func() { 1 }`} {
+        p := New(lexer.New(input))
+        p.ParseProgram()
+
+        if len(p.Errors()) == 0 {
+            t.Errorf("expected parse errors for %q", input)
+        }
+    }
+}
+
+func TestMacroLiteralParsing(t *testing.T) {
+    input := `macro(x, y) { x + y; }`
+
+    l := lexer.New(input)
+    p := New(l)
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    if len(program.Statements) != 1 {
+        t.Fatalf("program.Statements does not contain %d statements. got=%d", 1, len(program.Statements))
+    }
+
+    stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+    if !ok {
+        t.Fatalf("statement is not ast.ExpressionStatement. got=%T", program.Statements[0])
+    }
+
+    macro, ok := stmt.Expression.(*ast.MacroLiteral)
+    if !ok {
+        t.Fatalf("stmt.Expression is not ast.MacroLiteral. got=%T", stmt.Expression)
+    }
+
+    if len(macro.Parameters) != 2 {
+        t.Fatalf("macro literal parameters wrong. want 2, got=%d", len(macro.Parameters))
+    }
+
+    testLiteralExpression(t, macro.Parameters[0], "x")
+    testLiteralExpression(t, macro.Parameters[1], "y")
+
+    if macro.String() != "macro(x, y) (x + y)" {
+        t.Errorf("macro.String() wrong. got=%q", macro.String())
+    }
+
+    for _, input := range []string{"macro(x = 1) { x }", "macro(...xs) { xs }"} {
+        p := New(lexer.New(input))
+        p.ParseProgram()
+
+        if len(p.Errors()) == 0 {
+            t.Errorf("expected parse errors for %q", input)
+        }
+    }
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+    input := `
+import "lib/math" as m;
+export const limit = 10;
+let [a, b = 2, ...rest] = [1, ...xs];
+let {name, "full name": full = "none"} = {"name": "x", "full name": "y"};
+func add(x, y = 1, ...more) { return x + y; }
+let twice = (f) => f(f(1));
+let mac = macro(a, b) { quote(unquote(a) + unquote(b)) };
+defer print("done");
+let r = try { throw "bad"; } catch (e) { e } finally { 0 };
+if (!a < -b) { xs[1:2:-1] } else { xs[::2] };
+user?.name ?? user?.[0] |> add(1, y: 2);
+[x * 2 for x in 1..10 if x > 3];
+{k: v for k, v in h};
+0..<5;
+x in xs;
+match (a) { 1 => "one", [p, ...q] if p => q, {k} => k, _ => true }
+`
+
+    l := lexer.New(input)
+    p := New(l)
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    data, err := ast.MarshalNode(program)
+    if err != nil {
+        t.Fatalf("MarshalNode failed: %s", err)
+    }
+
+    decoded, err := ast.UnmarshalNode(data)
+    if err != nil {
+        t.Fatalf("UnmarshalNode failed: %s", err)
+    }
+
+    if !reflect.DeepEqual(decoded, program) {
+        t.Errorf("round trip changed the program.\nwant=%s\ngot=%s", program, decoded)
+    }
+}
+
+func TestTrace(t *testing.T) {
+    var out strings.Builder
+    p := New(lexer.New("a + b;"), Trace(&out))
+    p.ParseProgram()
+    checkParserErrors(t, p)
+
+    expected := `BEGIN parseStatement (cur=IDENT "a", peep=+ "+")
+    BEGIN parseExpressionStatement (cur=IDENT "a", peep=+ "+")
+        BEGIN parseExpression LOWEST (cur=IDENT "a", peep=+ "+")
+            BEGIN parseIdentifier (cur=IDENT "a", peep=+ "+")
+            END parseIdentifier (cur=IDENT "a")
+            LOWEST < SUM (peep=+ "+"): continue
+            BEGIN parseInfixExpression (cur=+ "+", peep=IDENT "b")
+                BEGIN parseExpression SUM (cur=IDENT "b", peep=; ";")
+                    BEGIN parseIdentifier (cur=IDENT "b", peep=; ";")
+                    END parseIdentifier (cur=IDENT "b")
+                END parseExpression SUM (cur=IDENT "b")
+            END parseInfixExpression (cur=IDENT "b")
+        END parseExpression LOWEST (cur=IDENT "b")
+    END parseExpressionStatement (cur=; ";")
+END parseStatement (cur=; ";")
+`
+
+    if out.String() != expected {
+        t.Errorf("wrong trace.\nwant=%s\ngot=%s", expected, out.String())
+    }
+}
@@ -0,0 +1,83 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// An Option configures a Parser created by New.
+type Option func(*Parser)
+
+// Trace makes the parser log to w the entry and exit of each parse function,
+// indented by nesting depth and with the current and peep tokens, along with
+// every precedence comparison made by parseExpression.
+func Trace(w io.Writer) Option {
+    return func(p *Parser) { p.tracer = w }
+}
+
+var precedenceNames = map[int]string{
+    LOWEST:         "LOWEST",
+    PIPE:           "PIPE",
+    COALESCE:       "COALESCE",
+    EQUALS:         "EQUALS",
+    LESSGREATER:    "LESSGREATER",
+    RANGE:          "RANGE",
+    SUM:            "SUM",
+    PRODUCT:        "PRODUCT",
+    PREFIX:         "PREFIX",
+    CALL:           "CALL",
+    INDEX:          "INDEX",
+}
+
+func (p *Parser) tracef(format string, args ...interface{}) {
+    fmt.Fprintf(p.tracer, "%s%s\n", strings.Repeat("    ", p.traceLevel), fmt.Sprintf(format, args...))
+}
+
+// trace logs the start of the named parse function and returns its name for
+// untrace, so calls read `defer p.untrace(p.trace("parseStatement"))`.
+func (p *Parser) trace(name string) string {
+    if p.tracer == nil {
+        return name
+    }
+
+    p.tracef("BEGIN %s (cur=%s %q, peep=%s %q)", name, p.curToken.Type, p.curToken.Literal, p.peepToken.Type, p.peepToken.Literal)
+    p.traceLevel++
+
+    return name
+}
+
+func (p *Parser) untrace(name string) {
+    if p.tracer == nil {
+        return
+    }
+
+    p.traceLevel--
+    p.tracef("END %s (cur=%s %q)", name, p.curToken.Type, p.curToken.Literal)
+}
+
+// fnName returns the method name of a registered prefix or infix function.
+func fnName(fn interface{}) string {
+    name := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
+    name = strings.TrimSuffix(name, "-fm")
+
+    return name[strings.LastIndex(name, ".") + 1:]
+}
+
+// continues reports whether an expression being parsed at precedence binds
+// the infix operator in peepToken, logging the comparison when tracing.
+func (p *Parser) continues(precedence int) bool {
+    peep := p.peepPrecedence()
+
+    if p.tracer != nil {
+        verdict := "stop"
+        if precedence < peep {
+            verdict = "continue"
+        }
+        p.tracef("%s < %s (peep=%s %q): %s", precedenceNames[precedence], precedenceNames[peep], p.peepToken.Type, p.peepToken.Literal, verdict)
+    }
+
+    return precedence < peep
+}
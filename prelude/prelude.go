@@ -0,0 +1,72 @@
+// Package prelude holds the parts of the standard library written in Elliott
+// itself. The source is embedded in the binary and parsed once.
+package prelude
+
+import (
+    _ "embed"
+    "fmt"
+    "sort"
+    "strings"
+    "sync"
+    "github.com/JakeNorman007/interpreter/ast"
+    "github.com/JakeNorman007/interpreter/evaluator"
+    "github.com/JakeNorman007/interpreter/lexer"
+    "github.com/JakeNorman007/interpreter/object"
+    "github.com/JakeNorman007/interpreter/parser"
+)
+
+//go:embed prelude.ell
+var source string
+
+var (
+    parseOnce   sync.Once
+    program     *ast.Program
+)
+
+// Program returns the parsed prelude. It panics if the embedded source does
+// not parse, which is a bug in the prelude rather than in user code.
+func Program() *ast.Program {
+    parseOnce.Do(func() {
+        p := parser.New(lexer.New(source))
+        program = p.ParseProgram()
+
+        if len(p.Errors()) != 0 {
+            panic(fmt.Sprintf("prelude: parse errors:\n\t%s", strings.Join(p.Errors(), "\n\t")))
+        }
+    })
+
+    return program
+}
+
+// Names returns the names the prelude defines at the top level in sorted
+// order. It reads them from the parsed program without evaluating it.
+func Names() []string {
+    names := []string{}
+    for _, stmt := range Program().Statements {
+        switch stmt := stmt.(type) {
+        case *ast.LetStatement:
+            if stmt.Pattern != nil {
+                names = append(names, ast.PatternNames(stmt.Pattern)...)
+            } else {
+                names = append(names, stmt.Name.Value)
+            }
+        case *ast.FunctionStatement:
+            names = append(names, stmt.Name.Value)
+        }
+    }
+    sort.Strings(names)
+
+    return names
+}
+
+// NewEnvironment returns a fresh environment holding the prelude's
+// definitions, suitable as the base of a script or REPL session.
+func NewEnvironment() *object.Environment {
+    env := object.NewEnvironment()
+
+    if result := evaluator.Eval(Program(), env); result != nil && result.Type() == object.ERROR_OBJ {
+        panic("prelude: " + result.Inspect())
+    }
+
+    return env
+}
@@ -0,0 +1,71 @@
+package prelude
+
+import (
+    "strings"
+    "testing"
+    "github.com/JakeNorman007/interpreter/evaluator"
+    "github.com/JakeNorman007/interpreter/lexer"
+    "github.com/JakeNorman007/interpreter/object"
+    "github.com/JakeNorman007/interpreter/parser"
+)
+
+func TestPrelude(t *testing.T) {
+    tests := []struct {
+        input       string
+        expected    string
+    }{
+        {"map([1, 2, 3], func(x) { x * 2 })", "[2, 4, 6]"},
+        {"map(1..3, func(x) { x + 1 })", "[2, 3, 4]"},
+        {"filter([1, 5, 2, 6], func(x) { x > 2 })", "[5, 6]"},
+        {"reduce([1, 2, 3, 4], func(acc, x) { acc * x }, 1)", "24"},
+        {"reduce([], func(acc, x) { acc + x }, 7)", "7"},
+        {"reduce(1..4, func(acc, x) { acc * x }, 1)", "24"},
+        {`reduce("abc", func(acc, x) { acc + x }, "")`, "abc"},
+        {"reduce(1..5, func(acc, x) { acc - x }, 0)", "-15"},
+        {"reduce([1], func(acc) { acc }, 0)", "ERROR:expected 1 arguments, got 2"},
+        {"reduce(1, func(acc, x) { acc }, 0)", "ERROR:INTEGER is not iterable"},
+        {"any(1, func(x) { true })", "ERROR:INTEGER is not iterable"},
+        {"any([1, 2], func(x) { missing })", "ERROR:identifier not found: missing"},
+        {"any([1, 2], func(x) { if (x == 1) { true } else { missing } })", "true"},
+        {"sum(1..100)", "5050"},
+        {"sum([])", "0"},
+        {"sum(1..100000)", "5000050000"},
+        {"any(1..100000, func(x) { x > 99999 })", "true"},
+        {"all(1..100000, func(x) { x > 0 })", "true"},
+        {"any([1, 2, 3], func(x) { x > 2 })", "true"},
+        {"any([], func(x) { true })", "false"},
+        {"all([1, 2, 3], func(x) { x > 0 })", "true"},
+        {"all([1, 2, 3], func(x) { x > 1 })", "false"},
+        {`"use strict"; let sum = 1; sum`, "1"},
+    }
+
+    for _, tt := range tests {
+        p := parser.New(lexer.New(tt.input))
+        program := p.ParseProgram()
+        if len(p.Errors()) != 0 {
+            t.Fatalf("parse errors for %q: %v", tt.input, p.Errors())
+        }
+
+        env := object.NewModuleEnvironment(NewEnvironment())
+        evaluated := evaluator.Eval(program, env)
+
+        if evaluated == nil || evaluated.Inspect() != tt.expected {
+            t.Errorf("wrong result for %q, expected=%q, got=%v", tt.input, tt.expected, evaluated)
+        }
+    }
+}
+
+func TestProgramIsParsedOnce(t *testing.T) {
+    if Program() != Program() {
+        t.Errorf("Program() parsed the prelude more than once")
+    }
+}
+
+func TestNamesMatchEnvironment(t *testing.T) {
+    names := Names()
+    bound := NewEnvironment().Names()
+
+    if strings.Join(names, " ") != strings.Join(bound, " ") {
+        t.Errorf("Names() = %v, want the names NewEnvironment binds, %v", names, bound)
+    }
+}
@@ -2,12 +2,17 @@ package repl
 
 import (
 	"io"
+	"os"
 	"fmt"
 	"bufio"
+	"strings"
+	"path/filepath"
+	"github.com/JakeNorman007/interpreter/ast"
 	"github.com/JakeNorman007/interpreter/evaluator"
 	"github.com/JakeNorman007/interpreter/lexer"
 	"github.com/JakeNorman007/interpreter/object"
 	"github.com/JakeNorman007/interpreter/parser"
+	"github.com/JakeNorman007/interpreter/prelude"
     "github.com/charmbracelet/lipgloss"
 )
 
@@ -15,9 +20,18 @@ const PROMPT = "::: "
 
 var logoStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#01FAC6"))
 
-func Start(in io.Reader, out io.Writer) {
+type Options struct {
+    Strict      bool //start in strict mode, as if the input began with "use strict"
+    NoPrelude   bool //skip loading the standard prelude
+}
+
+func Start(in io.Reader, out io.Writer, opts Options) {
     scanner := bufio.NewScanner(in)
-    env := object.NewEnvironment()
+    env := newEnvironment(opts)
+    macroEnv := object.NewEnvironment()
+
+    //one loader for the whole session, so each module is evaluated once
+    env.SetLoader(object.NewLoader())
 
     for {
         fmt.Printf(logoStyle.Render(PROMPT))
@@ -27,6 +41,11 @@ func Start(in io.Reader, out io.Writer) {
         }
 
         line := scanner.Text()
+        if strings.HasPrefix(line, ":") {
+            metaCommand(out, line)
+            continue
+        }
+
         l := lexer.New(line)
         p := parser.New(l)
 
@@ -35,9 +54,18 @@ func Start(in io.Reader, out io.Writer) {
             printParseErrors(out, p.Errors())
             continue
         }
-        
-        evaluated := evaluator.Eval(program, env)
-        if evaluated != nil {
+
+        evaluator.DefineMacros(program, macroEnv)
+        expanded, err := evaluator.ExpandMacros(program, macroEnv)
+        if err != nil {
+            io.WriteString(out, err.Inspect() + "\n")
+            continue
+        }
+
+        evaluated := evaluator.Eval(expanded, env)
+        if err, ok := evaluated.(*object.Error); ok {
+            io.WriteString(out, err.Trace() + "\n")
+        } else if evaluated != nil {
             io.WriteString(out, evaluated.Inspect())
             io.WriteString(out, "\n")
         }
@@ -45,6 +73,82 @@ func Start(in io.Reader, out io.Writer) {
 
 }
 
+// RunFile evaluates the script at path. Parse errors, or a runtime error that
+// reaches the top level, are returned as the error.
+func RunFile(path string, opts Options) error {
+    src, err := os.ReadFile(path)
+    if err != nil {
+        return err
+    }
+
+    l := lexer.New(string(src))
+    p := parser.New(l)
+
+    program := p.ParseProgram()
+    if len(p.Errors()) != 0 {
+        return fmt.Errorf("%s: parse errors:\n\t%s", path, strings.Join(p.Errors(), "\n\t"))
+    }
+
+    macroEnv := object.NewEnvironment()
+    evaluator.DefineMacros(program, macroEnv)
+    expanded, macroErr := evaluator.ExpandMacros(program, macroEnv)
+    if macroErr != nil {
+        return fmt.Errorf("%s: %s", path, macroErr.Inspect())
+    }
+
+    entry, err := filepath.Abs(path)
+    if err != nil {
+        return err
+    }
+
+    //the entry script counts as loading, so a cycle back to it is caught
+    //and every cycle is reported from the start of the run
+    loader := object.NewLoader()
+    loader.Loading = []string{entry}
+
+    env := newEnvironment(opts)
+    env.SetDir(filepath.Dir(path))
+    env.SetLoader(loader)
+
+    evaluated := evaluator.Eval(expanded, env)
+    if err, ok := evaluated.(*object.Error); ok {
+        return fmt.Errorf("%s: %s", path, err.Trace())
+    }
+
+    return nil
+}
+
+func newEnvironment(opts Options) *object.Environment {
+    var base *object.Environment
+    if !opts.NoPrelude {
+        base = prelude.NewEnvironment()
+    }
+
+    env := object.NewModuleEnvironment(base)
+    env.SetStrict(opts.Strict)
+
+    return env
+}
+
+// metaCommand runs a REPL command such as ":dot 1 + 2 * 3", which prints the
+// parse tree of its source as a Graphviz DOT graph.
+func metaCommand(out io.Writer, line string) {
+    name, src, _ := strings.Cut(line, " ")
+
+    switch name {
+    case ":dot":
+        p := parser.New(lexer.New(src))
+        program := p.ParseProgram()
+        if len(p.Errors()) != 0 {
+            printParseErrors(out, p.Errors())
+            return
+        }
+        io.WriteString(out, ast.DOT(program))
+    default:
+        io.WriteString(out, "unknown command " + name + ", try :dot <source>\n")
+    }
+}
+
 func printParseErrors(out io.Writer, errors []string) {
     for _, msg := range errors {
         io.WriteString(out, "\t" + msg + "\n")
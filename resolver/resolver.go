@@ -0,0 +1,326 @@
+// Package resolver works out, without running a program, which declaration
+// each identifier refers to. It builds a symbol table for every scope the
+// evaluator would create, records each identifier's symbol and collects the
+// names that resolve to nothing.
+//
+// As at runtime, a function body sees every name its enclosing scope ends
+// up declaring, so functions may call functions defined after them.
+package resolver
+
+import (
+    "sort"
+    "github.com/JakeNorman007/interpreter/ast"
+)
+
+type Resolution struct {
+    Definitions map[*ast.Identifier]Symbol //identifiers that declare a name
+    References  map[*ast.Identifier]Symbol //identifiers that refer to a declared name
+    Shadows     map[*ast.Identifier]Symbol //declarations that hide a builtin or an outer scope's name, with the symbol hidden
+    Undefined   []*ast.Identifier //references to names that are never declared, in source order
+    Tables      map[ast.Node]*SymbolTable //the table of each scope: the Program, function and macro literals, try expressions (for the catch block), match arms and comprehensions
+}
+
+// Uses returns the identifiers that refer to the name decl declares, in
+// source order.
+func (r *Resolution) Uses(decl *ast.Identifier) []*ast.Identifier {
+    uses := []*ast.Identifier{}
+    for ident, symbol := range r.References {
+        if symbol.Decl == decl {
+            uses = append(uses, ident)
+        }
+    }
+
+    sortIdentifiers(uses)
+
+    return uses
+}
+
+func sortIdentifiers(idents []*ast.Identifier) {
+    sort.SliceStable(idents, func(i, j int) bool {
+        a, b := idents[i].Token, idents[j].Token
+        return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+    })
+}
+
+type resolver struct {
+    table       *SymbolTable
+    result      *Resolution
+}
+
+// Resolve resolves every identifier in program. builtins names the builtin
+// functions, indexed in the order given, and globals the bindings that exist
+// before the program runs, such as the prelude's functions.
+func Resolve(program *ast.Program, builtins []string, globals ...string) *Resolution {
+    table := NewSymbolTable()
+    for i, name := range builtins {
+        table.DefineBuiltin(i, name)
+    }
+    for _, name := range globals {
+        table.DefineGlobal(name)
+    }
+
+    r := &resolver{
+        table: table,
+        result: &Resolution{
+            Definitions: map[*ast.Identifier]Symbol{},
+            References: map[*ast.Identifier]Symbol{},
+            Shadows: map[*ast.Identifier]Symbol{},
+            Undefined: []*ast.Identifier{},
+            Tables: map[ast.Node]*SymbolTable{program: table},
+        },
+    }
+
+    r.statements(program.Statements)
+    r.finish(table)
+
+    sortIdentifiers(r.result.Undefined)
+
+    return r.result
+}
+
+func (r *resolver) enter(node ast.Node, table *SymbolTable) {
+    r.result.Tables[node] = table
+    r.table = table
+}
+
+// leave resolves the function bodies of the current table, now that every
+// name they can see is declared, and returns to the enclosing table.
+func (r *resolver) leave() {
+    r.finish(r.table)
+    r.table = r.table.Outer
+}
+
+func (r *resolver) finish(table *SymbolTable) {
+    for len(table.pending) > 0 {
+        fn := table.pending[0]
+        table.pending = table.pending[1:]
+        r.functionBody(fn)
+    }
+}
+
+func (r *resolver) define(ident *ast.Identifier) {
+    if ident == nil || ident.Value == "_" {
+        return
+    }
+
+    if symbol, ok := r.table.store[ident.Value]; ok && symbol.Decl == nil {
+        r.result.Shadows[ident] = symbol
+    } else if symbol, ok := r.table.Outer.lookup(ident.Value); ok {
+        r.result.Shadows[ident] = symbol
+    }
+
+    r.result.Definitions[ident] = r.table.Define(ident)
+}
+
+func (r *resolver) resolve(ident *ast.Identifier) {
+    symbol, ok := r.table.Resolve(ident.Value)
+    if ok {
+        r.result.References[ident] = symbol
+        return
+    }
+
+    // self is bound by method calls on hashes
+    if ident.Value == "self" && r.inFunction() {
+        return
+    }
+
+    r.result.Undefined = append(r.result.Undefined, ident)
+}
+
+func (r *resolver) inFunction() bool {
+    for table := r.table; table != nil; table = table.Outer {
+        if table.function {
+            return true
+        }
+    }
+
+    return false
+}
+
+func (r *resolver) definePattern(pattern ast.Expression) {
+    switch pattern := pattern.(type) {
+    case *ast.Identifier:
+        r.define(pattern)
+    case *ast.DefaultPattern:
+        r.expression(pattern.Default)
+        r.definePattern(pattern.Pattern)
+    case *ast.ArrayPattern:
+        for _, el := range pattern.Elements {
+            r.definePattern(el)
+        }
+        r.define(pattern.Rest)
+    case *ast.HashPattern:
+        for _, value := range pattern.Values {
+            r.definePattern(value)
+        }
+    }
+}
+
+func (r *resolver) statements(statements []ast.Statement) {
+    for _, stmt := range statements {
+        r.statement(stmt)
+    }
+}
+
+func (r *resolver) statement(stmt ast.Statement) {
+    switch stmt := stmt.(type) {
+    case *ast.LetStatement:
+        r.expression(stmt.Value)
+        if stmt.Pattern != nil {
+            r.definePattern(stmt.Pattern)
+        } else {
+            r.define(stmt.Name)
+        }
+    case *ast.FunctionStatement:
+        r.define(stmt.Name)
+        r.expression(stmt.Function)
+    case *ast.ReturnStatement:
+        r.expression(stmt.ReturnValue)
+    case *ast.ThrowStatement:
+        r.expression(stmt.Value)
+    case *ast.DeferStatement:
+        r.expression(stmt.Call)
+    case *ast.ImportStatement:
+        r.define(stmt.Alias)
+    case *ast.ExportStatement:
+        r.statement(stmt.Statement)
+    case *ast.ExpressionStatement:
+        r.expression(stmt.Expression)
+    }
+}
+
+func (r *resolver) block(block *ast.BlockStatement) {
+    if block != nil {
+        r.statements(block.Statements)
+    }
+}
+
+func (r *resolver) expressions(exprs ...ast.Expression) {
+    for _, e := range exprs {
+        r.expression(e)
+    }
+}
+
+func (r *resolver) expression(e ast.Expression) {
+    switch e := e.(type) {
+    case *ast.Identifier:
+        r.resolve(e)
+    case *ast.PrefixExpression:
+        r.expression(e.Right)
+    case *ast.InfixExpression:
+        r.expressions(e.Left, e.Right)
+    case *ast.IfExpression:
+        r.expression(e.Condition)
+        r.block(e.Consequence)
+        r.block(e.Alternative)
+    case *ast.TryExpression:
+        r.block(e.Block)
+        if e.Catch != nil {
+            r.enter(e, NewEnclosedSymbolTable(r.table))
+            r.define(e.Param)
+            r.block(e.Catch)
+            r.leave()
+        }
+        r.block(e.Finally)
+    case *ast.FunctionLiteral, *ast.MacroLiteral:
+        r.table.pending = append(r.table.pending, e)
+    case *ast.CallExpression:
+        if ident, ok := e.Function.(*ast.Identifier); ok && ident.Value == "quote" {
+            r.quoted(e.Arguments)
+            return
+        }
+        r.expression(e.Function)
+        r.expressions(e.Arguments...)
+    case *ast.SpreadExpression:
+        r.expression(e.Value)
+    case *ast.KeywordArgument:
+        r.expression(e.Value)
+    case *ast.ArrayLiteral:
+        r.expressions(e.Elements...)
+    case *ast.HashLiteral:
+        for _, pair := range e.Pairs {
+            r.expressions(pair.Key, pair.Value)
+        }
+    case *ast.IndexExpression:
+        r.expressions(e.Left, e.Index)
+    case *ast.OptionalIndexExpression:
+        r.expressions(e.Left, e.Index)
+    case *ast.SliceExpression:
+        r.expressions(e.Left, e.Start, e.End, e.Step)
+    case *ast.PropertyExpression:
+        r.expression(e.Object)
+    case *ast.OptionalPropertyExpression:
+        r.expression(e.Object)
+    case *ast.RangeExpression:
+        r.expressions(e.Start, e.End)
+    case *ast.CoalesceExpression:
+        r.expressions(e.Left, e.Right)
+    case *ast.PipelineExpression:
+        r.expressions(e.Left, e.Right)
+    case *ast.ListComprehension:
+        r.expression(e.Iterable)
+        r.enter(e, NewEnclosedSymbolTable(r.table))
+        for _, variable := range e.Variables {
+            r.define(variable)
+        }
+        r.expressions(e.Condition, e.Element)
+        r.leave()
+    case *ast.HashComprehension:
+        r.expression(e.Iterable)
+        r.enter(e, NewEnclosedSymbolTable(r.table))
+        for _, variable := range e.Variables {
+            r.define(variable)
+        }
+        r.expressions(e.Condition, e.Key, e.Value)
+        r.leave()
+    case *ast.MatchExpression:
+        r.expression(e.Subject)
+        for _, arm := range e.Arms {
+            r.enter(arm, NewEnclosedSymbolTable(r.table))
+            r.definePattern(arm.Pattern)
+            r.expressions(arm.Guard, arm.Body)
+            r.leave()
+        }
+    }
+}
+
+// quoted resolves only the unquote(...) arguments inside a quote call; the
+// rest of the quoted code is resolved where a macro expands it.
+func (r *resolver) quoted(args []ast.Expression) {
+    for _, arg := range args {
+        ast.Inspect(arg, func(node ast.Node) bool {
+            call, ok := node.(*ast.CallExpression)
+            if !ok {
+                return true
+            }
+            if ident, ok := call.Function.(*ast.Identifier); ok && ident.Value == "unquote" {
+                r.expressions(call.Arguments...)
+                return false
+            }
+            return true
+        })
+    }
+}
+
+func (r *resolver) functionBody(fn ast.Expression) {
+    r.enter(fn, NewFunctionSymbolTable(r.table))
+
+    switch fn := fn.(type) {
+    case *ast.FunctionLiteral:
+        for i, param := range fn.Parameters {
+            if i < len(fn.Defaults) {
+                r.expression(fn.Defaults[i])
+            }
+            r.define(param)
+        }
+        r.define(fn.Rest)
+        r.block(fn.Body)
+    case *ast.MacroLiteral:
+        for _, param := range fn.Parameters {
+            r.define(param)
+        }
+        r.block(fn.Body)
+    }
+
+    r.leave()
+}
@@ -0,0 +1,157 @@
+package resolver
+
+import (
+    "fmt"
+    "reflect"
+    "sort"
+    "testing"
+    "github.com/JakeNorman007/interpreter/ast"
+    "github.com/JakeNorman007/interpreter/lexer"
+    "github.com/JakeNorman007/interpreter/parser"
+)
+
+var builtins = []string{"first", "len", "push"}
+
+func parse(t *testing.T, input string) *ast.Program {
+    p := parser.New(lexer.New(input))
+    program := p.ParseProgram()
+    if len(p.Errors()) != 0 {
+        t.Fatalf("parse errors: %v", p.Errors())
+    }
+
+    return program
+}
+
+// references lists each reference in source order as
+// "name@column SCOPE index decl-column", with "-" when there is no declaration site.
+func references(result *Resolution) []string {
+    idents := []*ast.Identifier{}
+    for ident := range result.References {
+        idents = append(idents, ident)
+    }
+    sort.Slice(idents, func(i, j int) bool { return idents[i].Token.Column < idents[j].Token.Column })
+
+    refs := []string{}
+    for _, ident := range idents {
+        symbol := result.References[ident]
+        decl := "-"
+        if symbol.Decl != nil {
+            decl = fmt.Sprint(symbol.Decl.Token.Column)
+        }
+        refs = append(refs, fmt.Sprintf("%s@%d %s %d %s", ident.Value, ident.Token.Column, symbol.Scope, symbol.Index, decl))
+    }
+
+    return refs
+}
+
+func names(idents []*ast.Identifier) []string {
+    result := []string{}
+    for _, ident := range idents {
+        result = append(result, ident.Value)
+    }
+
+    return result
+}
+
+func TestResolve(t *testing.T) {
+    tests := []struct {
+        input       string
+        expected    []string
+    }{
+        {"let a = 1; let b = a; len(b)", []string{"a@20 GLOBAL 0 5", "len@23 BUILTIN 1 -", "b@27 GLOBAL 1 16"}},
+        {"let f = func(x) { let y = x; y }; f", []string{"x@27 LOCAL 0 14", "y@30 LOCAL 1 23", "f@35 GLOBAL 0 5"}},
+        {"let f = func(x) { func() { x } }", []string{"x@28 FREE 0 14"}},
+        {"let f = func() { g() }; let g = func() { 1 }", []string{"g@18 GLOBAL 1 29"}},
+        {"let x = 1; let x = x + 1; x", []string{"x@20 GLOBAL 0 5", "x@27 GLOBAL 1 16"}},
+        {"let f = func(xs) { [x * 2 for x in xs if x > 0] }", []string{"x@21 LOCAL 0 31", "xs@36 LOCAL 0 14", "x@42 LOCAL 0 31"}},
+        {"let f = func(v) { match (v) { [h, ...t] => h + len(t), _ => v } }", []string{
+            "v@26 LOCAL 0 14", "h@44 LOCAL 0 32", "len@48 BUILTIN 1 -", "t@52 LOCAL 1 38", "v@61 LOCAL 0 14",
+        }},
+        {"try { 1 } catch (e) { e }", []string{"e@23 LOCAL 0 18"}},
+        {"let f = func(a, b = a) { b }", []string{"a@21 LOCAL 0 14", "b@26 LOCAL 1 17"}},
+        {"let [a, {b}] = [1, {\"b\": 2}]; a + b", []string{"a@31 GLOBAL 0 6", "b@35 GLOBAL 1 10"}},
+        {"import \"lib\" as m; m.sq(x: m)", []string{"m@20 GLOBAL 0 17", "m@28 GLOBAL 0 17"}},
+        {"let m = macro(a) { quote(unquote(a) + b) }", []string{"a@34 LOCAL 0 15"}},
+    }
+
+    for _, tt := range tests {
+        result := Resolve(parse(t, tt.input), builtins)
+
+        if got := references(result); !reflect.DeepEqual(got, tt.expected) {
+            t.Errorf("%s:\nwant=%q\ngot=%q", tt.input, tt.expected, got)
+        }
+    }
+}
+
+func TestUndefined(t *testing.T) {
+    tests := []struct {
+        input       string
+        globals     []string
+        expected    []string
+    }{
+        {"x; let x = 1; x", nil, []string{"x"}},
+        {"let f = func() { y + self }; self", nil, []string{"y", "self"}},
+        {"map([1], f)", []string{"map"}, []string{"f"}},
+        {"[x for x in xs]; x", nil, []string{"xs", "x"}},
+        {"quote(a + unquote(b))", nil, []string{"b"}},
+    }
+
+    for _, tt := range tests {
+        result := Resolve(parse(t, tt.input), builtins, tt.globals...)
+
+        if got := names(result.Undefined); !reflect.DeepEqual(got, tt.expected) {
+            t.Errorf("%s: want=%q, got=%q", tt.input, tt.expected, got)
+        }
+    }
+}
+
+func TestShadows(t *testing.T) {
+    input := "let len = 1; let map = 2; let x = 3; let f = func(x) { try { x } catch (len) { len } }"
+    result := Resolve(parse(t, input), builtins, "map")
+
+    expected := []string{"len@5 BUILTIN", "len@73 GLOBAL 5", "map@18 GLOBAL", "x@51 GLOBAL 31"}
+
+    got := []string{}
+    for ident, hidden := range result.Shadows {
+        entry := fmt.Sprintf("%s@%d %s", ident.Value, ident.Token.Column, hidden.Scope)
+        if hidden.Decl != nil {
+            entry += fmt.Sprintf(" %d", hidden.Decl.Token.Column)
+        }
+        got = append(got, entry)
+    }
+    sort.Strings(got)
+
+    if !reflect.DeepEqual(got, expected) {
+        t.Errorf("wrong shadows.\nwant=%q\ngot=%q", expected, got)
+    }
+}
+
+func TestTables(t *testing.T) {
+    input := "let f = func(a, b) { func(c) { a + c } }"
+    program := parse(t, input)
+    result := Resolve(program, builtins)
+
+    outer := program.Statements[0].(*ast.LetStatement).Value.(*ast.FunctionLiteral)
+    inner := outer.Body.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+
+    if table := result.Tables[outer]; table == nil || table.Outer != result.Tables[program] || len(table.FreeSymbols) != 0 {
+        t.Fatalf("wrong table for the outer function: %+v", table)
+    }
+
+    table := result.Tables[inner]
+    if table == nil || table.Outer != result.Tables[outer] {
+        t.Fatalf("wrong table for the inner function: %+v", table)
+    }
+
+    if len(table.FreeSymbols) != 1 || table.FreeSymbols[0].Name != "a" || table.FreeSymbols[0].Scope != LocalScope {
+        t.Errorf("wrong free symbols, got=%+v", table.FreeSymbols)
+    }
+
+    uses := result.Uses(outer.Parameters[0])
+    if len(uses) != 1 || uses[0].Token.Column != 32 {
+        t.Errorf("wrong uses of a, got=%v", names(uses))
+    }
+    if uses := result.Uses(outer.Parameters[1]); len(uses) != 0 {
+        t.Errorf("expected no uses of b, got=%v", names(uses))
+    }
+}
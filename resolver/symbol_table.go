@@ -0,0 +1,124 @@
+package resolver
+
+import "github.com/JakeNorman007/interpreter/ast"
+
+type SymbolScope string
+
+const (
+    GlobalScope     SymbolScope = "GLOBAL"
+    LocalScope      SymbolScope = "LOCAL"
+    BuiltinScope    SymbolScope = "BUILTIN"
+    FreeScope       SymbolScope = "FREE"
+)
+
+// A Symbol is a name as seen from one symbol table. Index is the name's slot
+// in the table that defines it or, for a FreeScope symbol, its position in
+// FreeSymbols.
+type Symbol struct {
+    Name    string
+    Scope   SymbolScope
+    Index   int
+    Decl    *ast.Identifier //where the name is declared, nil for builtins and predeclared globals
+}
+
+// A SymbolTable holds the names of one environment the evaluator creates:
+// the top level, a function call, or a block scope such as a catch block,
+// match arm or comprehension item.
+type SymbolTable struct {
+    Outer           *SymbolTable
+    FreeSymbols     []Symbol //the symbols of enclosing functions that a function captures
+    store           map[string]Symbol
+    numDefinitions  int
+    global          bool
+    function        bool
+    pending         []ast.Expression //function and macro literals to resolve once the table is complete
+}
+
+// NewSymbolTable returns a table for the top level of a program.
+func NewSymbolTable() *SymbolTable {
+    return &SymbolTable{store: map[string]Symbol{}, global: true}
+}
+
+// NewEnclosedSymbolTable returns a table for a block scope inside outer.
+// Names of the enclosing function resolve through it unchanged.
+func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
+    return &SymbolTable{Outer: outer, store: map[string]Symbol{}}
+}
+
+// NewFunctionSymbolTable returns a table for a function body inside outer.
+// Locals of enclosing functions resolve to FreeScope symbols.
+func NewFunctionSymbolTable(outer *SymbolTable) *SymbolTable {
+    table := NewEnclosedSymbolTable(outer)
+    table.function = true
+
+    return table
+}
+
+// Define declares ident in the table, replacing any earlier symbol of the
+// same name.
+func (s *SymbolTable) Define(ident *ast.Identifier) Symbol {
+    symbol := Symbol{Name: ident.Value, Index: s.numDefinitions, Decl: ident}
+    if s.global {
+        symbol.Scope = GlobalScope
+    } else {
+        symbol.Scope = LocalScope
+    }
+
+    s.store[ident.Value] = symbol
+    s.numDefinitions++
+
+    return symbol
+}
+
+// DefineGlobal declares a global that exists before the program runs, such
+// as a prelude function.
+func (s *SymbolTable) DefineGlobal(name string) Symbol {
+    symbol := Symbol{Name: name, Scope: GlobalScope, Index: s.numDefinitions}
+    s.store[name] = symbol
+    s.numDefinitions++
+
+    return symbol
+}
+
+func (s *SymbolTable) DefineBuiltin(index int, name string) Symbol {
+    symbol := Symbol{Name: name, Scope: BuiltinScope, Index: index}
+    s.store[name] = symbol
+
+    return symbol
+}
+
+func (s *SymbolTable) defineFree(original Symbol) Symbol {
+    s.FreeSymbols = append(s.FreeSymbols, original)
+
+    symbol := Symbol{Name: original.Name, Scope: FreeScope, Index: len(s.FreeSymbols) - 1, Decl: original.Decl}
+    s.store[original.Name] = symbol
+
+    return symbol
+}
+
+// Resolve looks name up through the enclosing tables. A function table
+// that finds a local of an enclosing function records it as free.
+func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
+    symbol, ok := s.store[name]
+    if ok || s.Outer == nil {
+        return symbol, ok
+    }
+
+    symbol, ok = s.Outer.Resolve(name)
+    if !ok || symbol.Scope == GlobalScope || symbol.Scope == BuiltinScope || !s.function {
+        return symbol, ok
+    }
+
+    return s.defineFree(symbol), true
+}
+
+// lookup finds the symbol name refers to without recording free symbols.
+func (s *SymbolTable) lookup(name string) (Symbol, bool) {
+    for ; s != nil; s = s.Outer {
+        if symbol, ok := s.store[name]; ok {
+            return symbol, true
+        }
+    }
+
+    return Symbol{}, false
+}
@@ -0,0 +1,56 @@
+package resolver
+
+import (
+    "testing"
+    "github.com/JakeNorman007/interpreter/ast"
+)
+
+func TestSymbolTable(t *testing.T) {
+    a, b, c, d := &ast.Identifier{Value: "a"}, &ast.Identifier{Value: "b"}, &ast.Identifier{Value: "c"}, &ast.Identifier{Value: "d"}
+
+    global := NewSymbolTable()
+    global.DefineBuiltin(0, "len")
+    global.Define(a)
+
+    first := NewFunctionSymbolTable(global)
+    first.Define(b)
+
+    block := NewEnclosedSymbolTable(first)
+    block.Define(c)
+
+    second := NewFunctionSymbolTable(block)
+    second.Define(d)
+
+    tests := []struct {
+        table       *SymbolTable
+        name        string
+        expected    Symbol
+    }{
+        {second, "len", Symbol{Name: "len", Scope: BuiltinScope, Index: 0}},
+        {second, "a", Symbol{Name: "a", Scope: GlobalScope, Index: 0, Decl: a}},
+        {second, "d", Symbol{Name: "d", Scope: LocalScope, Index: 0, Decl: d}},
+        {second, "b", Symbol{Name: "b", Scope: FreeScope, Index: 0, Decl: b}},
+        {second, "c", Symbol{Name: "c", Scope: FreeScope, Index: 1, Decl: c}},
+        {second, "b", Symbol{Name: "b", Scope: FreeScope, Index: 0, Decl: b}},
+        {block, "b", Symbol{Name: "b", Scope: LocalScope, Index: 0, Decl: b}},
+    }
+
+    for _, tt := range tests {
+        symbol, ok := tt.table.Resolve(tt.name)
+        if !ok {
+            t.Errorf("%s not resolvable", tt.name)
+            continue
+        }
+        if symbol != tt.expected {
+            t.Errorf("%s: expected %+v, got %+v", tt.name, tt.expected, symbol)
+        }
+    }
+
+    if len(second.FreeSymbols) != 2 || second.FreeSymbols[1].Scope != LocalScope || second.FreeSymbols[1].Decl != c {
+        t.Errorf("wrong free symbols, got=%+v", second.FreeSymbols)
+    }
+
+    if _, ok := second.Resolve("e"); ok {
+        t.Errorf("e should not resolve")
+    }
+}
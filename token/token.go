@@ -5,12 +5,15 @@ type TokenType string
 type Token struct{
     Type    TokenType
     Literal string
+    Line    int //1-based line of the token's first character, 0 if unknown
+    Column  int //1-based column of the token's first character, in bytes
 }
 
 const(
     // Sorts of errors
     ILLEGAL = "ILLEGAL"
     EOF = "EOF"
+    COMMENT = "COMMENT" // a // comment, skipped by the parser
 
     // Identifiers, literals
     IDENT = "IDENT" // add, foobar, x, y
@@ -31,6 +34,8 @@ const(
     COMMA = ","
     SEMICOLON = ";"
     COLON = ":"
+    ELLIPSIS = "..."
+    DOT = "."
 
     LEFTPAREN = "("
     RIGHTPAREN = ")"
@@ -42,15 +47,34 @@ const(
     // Keywords
     FUNCTION = "FUNCTION"
     LET = "LET"
+    CONST = "CONST"
     TRUE = "TRUE"
     FALSE = "FALSE"
     IF = "IF"
     ELSE = "ELSE"
     RETURN = "RETURN"
+    MATCH = "MATCH"
+    THROW = "THROW"
+    TRY = "TRY"
+    CATCH = "CATCH"
+    FINALLY = "FINALLY"
+    DEFER = "DEFER"
+    IN = "IN"
+    FOR = "FOR"
+    IMPORT = "IMPORT"
+    EXPORT = "EXPORT"
+    AS = "AS"
+    MACRO = "MACRO"
 
     /// Doubles
     EQUAL = "=="
     NOT_EQUAL = "!="
+    ARROW = "=>"
+    COALESCE = "??"
+    OPTIONAL_CHAIN = "?."
+    PIPE = "|>"
+    RANGE = ".."
+    RANGE_EXCLUSIVE = "..<"
 
     /// String
     STRING = "STRING"
@@ -59,11 +83,24 @@ const(
 var keywords = map[string]TokenType {
     "func": FUNCTION,
     "let": LET,
+    "const": CONST,
     "true": TRUE,
     "false": FALSE,
     "if": IF,
     "else": ELSE,
     "return": RETURN,
+    "match": MATCH,
+    "throw": THROW,
+    "try": TRY,
+    "catch": CATCH,
+    "finally": FINALLY,
+    "defer": DEFER,
+    "in": IN,
+    "for": FOR,
+    "import": IMPORT,
+    "export": EXPORT,
+    "as": AS,
+    "macro": MACRO,
 }
 
 func LookupIdent(ident string) TokenType {